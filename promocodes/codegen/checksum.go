@@ -0,0 +1,84 @@
+package codegen
+
+import "strings"
+
+// checksumAlphabet holds the characters covered by the checksum. Letters are
+// matched case-insensitively; any other character, such as a separator, is
+// ignored.
+const checksumAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// checksumValues maps the characters of code that count towards the checksum
+// to their position in checksumAlphabet
+func checksumValues(code string) []int {
+	var values []int
+	for _, r := range strings.ToLower(code) {
+		if i := strings.IndexRune(checksumAlphabet, r); i >= 0 {
+			values = append(values, i)
+		}
+	}
+	return values
+}
+
+// checkChar returns the check character for code, computed with the Luhn mod
+// N algorithm over checksumAlphabet (N = 36). Going from the rightmost
+// character, every second value is doubled and its base-N digits summed;
+// the check character is the one that brings the total to a multiple of N.
+// This catches any single mistyped character and most swaps of adjacent
+// characters.
+func checkChar(code string) byte {
+	n := len(checksumAlphabet)
+	values := checksumValues(code)
+	sum, factor := 0, 2
+	for i := len(values) - 1; i >= 0; i-- {
+		addend := factor * values[i]
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return checksumAlphabet[(n-sum%n)%n]
+}
+
+// luhnDigit returns the Luhn check digit for the decimal digits of number,
+// the same algorithm as checkChar with N = 10
+func luhnDigit(number string) byte {
+	sum, double := 0, true
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// VerifyLuhn reports whether number, made only of decimal digits with any
+// spaces or hyphens between them ignored, ends in a valid Luhn check digit,
+// as do numeric codes (see Options.Numeric) and card numbers
+func VerifyLuhn(number string) bool {
+	number = strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if len(number) < 2 || strings.IndexFunc(number, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return false
+	}
+	return luhnDigit(number[:len(number)-1]) == number[len(number)-1]
+}
+
+// Verify reports whether the last letter or digit of code is a valid check
+// character for the rest of it, as appended by Options.Checksum. Case and
+// characters other than ASCII letters and digits are ignored.
+func Verify(code string) bool {
+	n := len(checksumAlphabet)
+	values := checksumValues(code)
+	if len(values) < 2 {
+		return false
+	}
+	sum, factor := 0, 1
+	for i := len(values) - 1; i >= 0; i-- {
+		addend := factor * values[i]
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return sum%n == 0
+}
@@ -0,0 +1,87 @@
+package codegen
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	codes, err := Generate(testWords, 20, Options{WordsPerCode: 2, Separator: "-", Digits: 2, Checksum: true, Rand: rand.New(rand.NewSource(1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if !Verify(code) {
+			t.Errorf("Verify(%q) = false for a generated code", code)
+		}
+
+		// Any single changed letter or digit must be caught
+		b := []byte(code)
+		for i, c := range b {
+			if c == '-' {
+				continue
+			}
+			b[i] = checksumAlphabet[(indexOf(c)+1)%len(checksumAlphabet)]
+			if Verify(string(b)) {
+				t.Errorf("Verify(%q) = true after changing %q", b, code)
+			}
+			b[i] = c
+		}
+	}
+}
+
+func TestVerifyIgnoresCaseAndSeparators(t *testing.T) {
+	code := "apple-tree-" + string(checkChar("apple-tree"))
+	for _, variant := range []string{code, "APPLE TREE " + code[len(code)-1:], "appletree" + code[len(code)-1:]} {
+		if !Verify(variant) {
+			t.Errorf("Verify(%q) = false", variant)
+		}
+	}
+	for _, bad := range []string{"", "a", "---"} {
+		if Verify(bad) {
+			t.Errorf("Verify(%q) = true", bad)
+		}
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	// No words are needed, and the space holds 10^(n-1) codes
+	if total, err := MaxCombinations(nil, Options{Numeric: 4}); err != nil || total != 1000 {
+		t.Errorf("MaxCombinations of 4-digit numeric codes = %d, %v; want 1000", total, err)
+	}
+	codes, err := Generate(nil, 200, Options{Numeric: 4, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if len(code) != 4 || !VerifyLuhn(code) {
+			t.Errorf("generated %q, want 4 digits ending in a Luhn check digit", code)
+		}
+	}
+
+	for number, want := range map[string]bool{
+		"79927398713":         true, // the usual example
+		"4111 1111 1111 1111": true,
+		"79927398710":         false,
+		"7992739871a":         false,
+		"0":                   false,
+	} {
+		if got := VerifyLuhn(number); got != want {
+			t.Errorf("VerifyLuhn(%q) = %v, want %v", number, got, want)
+		}
+	}
+	if _, err := Generate(nil, 1, Options{Numeric: 1}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Numeric 1: error = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
+// indexOf returns the position of c in checksumAlphabet
+func indexOf(c byte) int {
+	for i := range len(checksumAlphabet) {
+		if checksumAlphabet[i] == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,752 @@
+// Package codegen generates unique, memorable promo codes from dictionary
+// words.
+package codegen
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Letter case styles for the words of a code
+const (
+	CaseLower = "lower" // apple-tree-lamp
+	CaseUpper = "upper" // APPLE-TREE-LAMP
+	CaseTitle = "title" // Apple-Tree-Lamp
+	CaseKeep  = "keep"  // iPhone-tree-Lamp, as written in the dictionary
+)
+
+// Options controls how Generate builds each code
+type Options struct {
+	// Format is a template for the codes, such as "{word}_{WORD}-{digits:4}"
+	// (see the placeholders below). When set, it replaces WordsPerCode,
+	// Separator, Digits, Prefix and Suffix.
+	//
+	//	{word}      a random word in the Case style
+	//	{Word}      a random word in title case
+	//	{WORD}      a random word in upper case
+	//	{digits:N}  N random digits
+	//	{number:N}  a random number from 0 to N
+	Format string
+
+	WordsPerCode int
+
+	// WordsMax, if above WordsPerCode, gives each code from WordsPerCode to
+	// WordsMax words. Each length is equally likely, unless Exhaustive is
+	// set or a request covers most of the possible codes, when each code is.
+	WordsMax int
+
+	Separator    string
+	Distinct     bool   // never repeat a word within a code
+	Alliterative bool   // draw all words of a code from those sharing a first letter
+	EqualLength  bool   // draw all words of a code from those of the same length
+	FirstLetter  rune   // if not 0, start every code with a word beginning with this letter, ignoring case
+	Case         string // one of CaseLower (the default if empty), CaseUpper, CaseTitle or CaseKeep
+	Digits       int    // random digits appended to each code, 0 for none
+
+	// ExcludeAmbiguous leaves out the digits 0 and 1, which are easily
+	// mistaken for the letters O, l and I
+	ExcludeAmbiguous bool
+
+	// NumberMax adds a random number from 0 to NumberMax to each code when
+	// it is positive, placed as set by NumberPosition (NumberSuffix if empty)
+	NumberMax      int
+	NumberPosition string
+	Prefix         string // fixed text placed before each code, if not empty
+	Suffix         string // fixed text placed after each code, if not empty
+
+	// Numeric, if positive, makes every code a number of this many digits,
+	// the last a Luhn check digit as on card numbers (see VerifyLuhn),
+	// instead of words. It replaces every other option shaping the codes, so
+	// no words are needed.
+	Numeric int
+
+	// Checksum appends a check character to each code, after the separator
+	// (or directly after a Format), so typos can be caught with Verify
+	Checksum bool
+
+	// Weights biases word selection towards words with larger weights, keyed
+	// by lowercase word. Words left out get a tenth of the smallest weight.
+	// Weights are ignored when a request covers most of the possible codes,
+	// or with Exhaustive.
+	Weights map[string]float64
+
+	// MinUniqueWords re-rolls codes that would leave fewer than this many
+	// distinct words across the whole batch, 0 for no minimum
+	MinUniqueWords int
+
+	// UniqueWords never uses a word twice in the whole batch, ignoring case,
+	// dealing each word out of the dictionary at most once, so the
+	// dictionary must hold at least count times WordsPerCode words. Weights
+	// are ignored with it.
+	UniqueWords bool
+
+	// MinDistance re-rolls codes fewer than this many edits (insertions,
+	// deletions or substitutions) away from an earlier code of the batch, so
+	// that a mistyped code is not another valid one, 0 for no minimum. Every
+	// code is compared with all those before it, so generation slows with the
+	// square of the count: expect seconds for ten thousand codes. Codes in
+	// Used are not compared with.
+	MinDistance int
+
+	// Exhaustive always generates codes by decoding distinct random indexes
+	// into the space of possible codes, which never re-rolls a duplicate,
+	// instead of only when a request covers most of it
+	Exhaustive bool
+
+	// RejectSubstrings discards codes containing any of these, ignoring case,
+	// either as generated or with the separators removed
+	RejectSubstrings []string
+
+	// Match, if not nil, discards codes it does not match, for constraints
+	// no other option covers
+	Match *regexp.Regexp
+
+	Seed   int64
+	Secure bool            // use crypto/rand instead of Seed
+	Rand   *rand.Rand      // if not nil, the source of randomness instead of Seed or Secure
+	Used   map[string]bool // codes that must not be generated again
+	Stats  *Stats          // if not nil, filled in with statistics about the generation
+}
+
+// Stats describes how a batch of codes was generated
+type Stats struct {
+	Combinations int            // possible codes, saturating at math.MaxInt
+	Generated    int            // codes passed on
+	Rerolls      int            // words and codes drawn again because they repeated an earlier one or were rejected
+	Words        map[string]int // times each dictionary word appears in the codes passed on
+}
+
+// add counts a code made of the words picked
+func (s *Stats) add(picked []string) {
+	s.Generated++
+	for _, word := range picked {
+		s.Words[word]++
+	}
+}
+
+// Positions of the number within a code, see Options.NumberMax
+const (
+	NumberPrefix = "prefix" // 42-apple-tree-lamp
+	NumberMiddle = "middle" // apple-42-tree-lamp
+	NumberSuffix = "suffix" // apple-tree-lamp-42
+)
+
+// Characters the digits of codes are drawn from
+const (
+	allDigits         = "0123456789"
+	unambiguousDigits = "23456789" // for Options.ExcludeAmbiguous
+)
+
+// digitSet returns the characters the digits of codes are drawn from
+func digitSet(opts Options) string {
+	if opts.ExcludeAmbiguous {
+		return unambiguousDigits
+	}
+	return allDigits
+}
+
+// ValidCase reports whether style is a known case style
+func ValidCase(style string) bool {
+	switch style {
+	case "", CaseLower, CaseUpper, CaseTitle, CaseKeep:
+		return true
+	}
+	return false
+}
+
+// applyCase returns word rewritten in the given case style
+func applyCase(word, style string) string {
+	switch style {
+	case CaseUpper:
+		return strings.ToUpper(word)
+	case CaseTitle:
+		r, size := utf8.DecodeRuneInString(word)
+		return string(unicode.ToUpper(r)) + strings.ToLower(word[size:])
+	case CaseKeep:
+		return word
+	default:
+		return strings.ToLower(word)
+	}
+}
+
+// CombinationCount returns the number of possible codes drawn from n words
+// with opts, saturating at math.MaxInt instead of overflowing. For r words
+// and d digits per code that is n^r * 10^d, or nPr * 10^d when opts.Distinct
+// is set (with 8 instead of 10 when opts.ExcludeAmbiguous is). It returns 0
+// if opts are invalid, and ignores opts.Alliterative and opts.EqualLength,
+// which depend on the words themselves.
+func CombinationCount(n int, opts Options) int {
+	layouts, err := newLayouts(opts)
+	if err != nil {
+		return 0
+	}
+	total := 0
+	for _, l := range layouts {
+		total = saturatingAdd(total, combinationCount(n, n, l, opts))
+	}
+	return total
+}
+
+// combinationCount returns the number of possible codes of layout l drawn
+// from n words with opts, the first of which comes from only the first lead
+// of them, saturating at math.MaxInt
+func combinationCount(n, lead int, l layout, opts Options) int {
+	total := 1
+	for i := 0; i < l.words; i++ {
+		if i == 0 {
+			total = saturatingMul(total, lead)
+		} else if opts.Distinct {
+			total = saturatingMul(total, max(n-i, 0))
+		} else {
+			total = saturatingMul(total, n)
+		}
+	}
+	for i := 0; i < l.digits; i++ {
+		total = saturatingMul(total, len(digitSet(opts)))
+	}
+	for _, n := range l.numbers {
+		total = saturatingMul(total, n+1)
+	}
+	return total
+}
+
+// combinationBits returns log2 of combinationCount(n, lead, l, opts),
+// without saturating
+func combinationBits(n, lead int, l layout, opts Options) float64 {
+	bits := 0.0
+	for i := 0; i < l.words; i++ {
+		switch {
+		case i == 0:
+			bits += math.Log2(float64(lead))
+		case opts.Distinct:
+			bits += math.Log2(float64(max(n-i, 0)))
+		default:
+			bits += math.Log2(float64(n))
+		}
+	}
+	bits += float64(l.digits) * math.Log2(float64(len(digitSet(opts))))
+	for _, n := range l.numbers {
+		bits += math.Log2(float64(n + 1))
+	}
+	return bits
+}
+
+// maxRejections bounds how many codes in a row may be discarded, as repeats,
+// for containing one of Options.RejectSubstrings or for adding too few new
+// words to reach Options.MinUniqueWords, before GenerateStream gives up.
+// Nearly full spaces allow rejectionFactor times the draws a new code is
+// expected to take instead, if that is more.
+const (
+	maxRejections   = 10000
+	rejectionFactor = 100
+)
+
+// rejectionLimit returns how many codes in a row may be discarded when used
+// of the total possible codes have been generated or excluded
+func rejectionLimit(total, used int) int {
+	return max(maxRejections, saturatingMul(rejectionFactor, total/max(total-used, 1)))
+}
+
+// varied reports whether a code of the words picked keeps opts.MinUniqueWords
+// within reach: the distinct words already used, those it adds, and up to
+// perCode new ones for each of the remaining codes after it
+func varied(used map[string]int, picked []string, remaining, perCode int, opts Options) bool {
+	if opts.MinUniqueWords == 0 {
+		return true
+	}
+	unique := len(used)
+	for i, word := range picked {
+		if used[word] == 0 && !slices.Contains(picked[:i], word) {
+			unique++
+		}
+	}
+	return unique+saturatingMul(remaining, perCode) >= opts.MinUniqueWords
+}
+
+// checkUniqueWords reports an error if count codes with perCode words each,
+// drawn from groups, cannot contain opts.MinUniqueWords distinct words
+func checkUniqueWords(groups [][]string, count, perCode int, opts Options) error {
+	if opts.MinUniqueWords < 0 {
+		return errorf(ErrInvalidOptions, "minimum unique words must not be negative (got %d)", opts.MinUniqueWords)
+	}
+	if opts.MinUniqueWords == 0 {
+		return nil
+	}
+	if most := saturatingMul(count, perCode); opts.MinUniqueWords > most {
+		return errorf(ErrInvalidOptions, "%d codes cannot contain %d unique words (at most %d, from %d per code)", count, opts.MinUniqueWords, most, perCode)
+	}
+	available := make(map[string]bool)
+	for _, g := range groups {
+		for _, word := range g {
+			available[word] = true
+		}
+	}
+	if opts.MinUniqueWords > len(available) {
+		return errorf(ErrInsufficientWords, "insufficient words in dictionary for %d unique words (only %d usable)", opts.MinUniqueWords, len(available))
+	}
+	return nil
+}
+
+// rejected reports whether code contains one of opts.RejectSubstrings,
+// ignoring case, as is or with opts.Separator removed
+func rejected(code string, opts Options) bool {
+	if len(opts.RejectSubstrings) == 0 {
+		return false
+	}
+	code = strings.ToLower(code)
+	joined := code
+	if opts.Separator != "" {
+		joined = strings.ReplaceAll(code, strings.ToLower(opts.Separator), "")
+	}
+	for _, sub := range opts.RejectSubstrings {
+		sub = strings.ToLower(sub)
+		if sub != "" && (strings.Contains(code, sub) || strings.Contains(joined, sub)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mismatched reports whether code fails to match opts.Match
+func mismatched(code string, opts Options) bool {
+	return opts.Match != nil && !opts.Match.MatchString(code)
+}
+
+// saturatingMul returns a*b for non-negative a and b, or math.MaxInt if the
+// product would overflow
+func saturatingMul(a, b int) int {
+	if b != 0 && a > math.MaxInt/b {
+		return math.MaxInt
+	}
+	return a * b
+}
+
+// saturatingAdd returns a+b for non-negative a and b, or math.MaxInt if the
+// sum would overflow
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// wordPicker chooses a random index in [0, n) when selecting words
+type wordPicker interface {
+	Intn(n int) int
+}
+
+// cryptoPicker is a wordPicker backed by crypto/rand, for codes that must not
+// be predictable
+type cryptoPicker struct{}
+
+// Intn returns a uniformly distributed random index in [0, n)
+func (cryptoPicker) Intn(n int) int {
+	v, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return int(v.Int64())
+}
+
+// newPicker returns the wordPicker selected by opts
+func newPicker(opts Options) wordPicker {
+	if opts.Rand != nil {
+		return opts.Rand
+	}
+	if opts.Secure {
+		return cryptoPicker{}
+	}
+	return rand.New(rand.NewSource(opts.Seed))
+}
+
+// Generate generates count unique promo codes of opts.WordsPerCode words,
+// joining the words of each code with opts.Separator, or laid out by
+// opts.Format if it is set. Unless opts.Secure is set, the same words, count
+// and options always produce the same codes in the same order.
+func Generate(words []string, count int, opts Options) ([]string, error) {
+	return GenerateContext(context.Background(), words, count, opts)
+}
+
+// GenerateContext generates the same codes as Generate, but stops early once
+// ctx is done, returning the codes produced so far along with ctx.Err().
+func GenerateContext(ctx context.Context, words []string, count int, opts Options) ([]string, error) {
+	codes := make([]string, 0, count)
+	err := GenerateStreamContext(ctx, words, count, opts, func(code string) error {
+		codes = append(codes, code)
+		return nil
+	})
+	return codes, err
+}
+
+// Shuffle puts codes in a random order, independent of the order they were
+// generated in, drawn like their words from opts.Rand, crypto/rand with
+// opts.Secure or else opts.Seed
+func Shuffle(codes []string, opts Options) {
+	shuffle(codes, newPicker(opts))
+}
+
+// shuffle puts s in a random order drawn from picker
+func shuffle(s []string, picker wordPicker) {
+	for i := len(s) - 1; i > 0; i-- {
+		j := picker.Intn(i + 1)
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// MaxCombinations returns the number of unique codes that can be generated
+// from words with opts, saturating at math.MaxInt. Unlike CombinationCount
+// it accounts for every option, but not for opts.Used.
+func MaxCombinations(words []string, opts Options) (int, error) {
+	sp, err := newSpace(words, opts)
+	return sp.total, err
+}
+
+// RemainingCombinations returns the number of codes MaxCombinations counts
+// that are left once opts.Used is excluded, and how many codes of opts.Used
+// were excluded. Only used codes shaped like the generated ones count, so
+// remaining is never negative.
+func RemainingCombinations(words []string, opts Options) (remaining, excluded int, err error) {
+	sp, err := newSpace(words, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	return sp.total - sp.used, sp.used, nil
+}
+
+// Entropy returns the estimated entropy in bits of a code generated from
+// words with opts: log2 of the number of possible codes, as counted by
+// MaxCombinations but without saturating. It assumes every code is equally
+// likely, so it overestimates the entropy when opts.Weights is set.
+func Entropy(words []string, opts Options) (float64, error) {
+	sp, err := newSpace(words, opts)
+	if err != nil {
+		return 0, err
+	}
+	// Sum the groups' counts as powers of two, scaled down by the largest to
+	// stay within range
+	bits := make([]float64, len(sp.groups))
+	for i, g := range sp.groups {
+		bits[i] = combinationBits(len(g), sp.leads[i], sp.layouts[i], opts)
+	}
+	largest := slices.Max(bits)
+	sum := 0.0
+	for _, b := range bits {
+		sum += math.Exp2(b - largest)
+	}
+	return largest + math.Log2(sum), nil
+}
+
+// space describes all the codes that can be generated from a dictionary
+type space struct {
+	layouts  []layout   // layout of the codes drawn from each group
+	groups   [][]string // pools each code draws all of its words from
+	leads    []int      // number of words at the start of each group the first word of a code is drawn from
+	sizes    []int      // number of codes drawn from each group
+	spans    []span     // the groups of each code length, shortest first
+	maxWords int        // most words in a code
+	total    int        // sum of sizes, saturating at math.MaxInt
+	used     int        // codes of Options.Used shaped like codes of the space, at most total
+}
+
+// span is a run of the groups of a space whose codes have the same length
+type span struct {
+	start, end int // indexes of the groups
+	total      int // sum of their sizes, saturating at math.MaxInt
+}
+
+// newLayouts returns the layouts of the codes described by opts, one per
+// number of words from opts.WordsPerCode to opts.WordsMax
+func newLayouts(opts Options) ([]layout, error) {
+	if opts.Numeric != 0 {
+		l, err := newLayout(opts)
+		if err != nil {
+			return nil, err
+		}
+		return []layout{l}, nil
+	}
+	most := opts.WordsPerCode
+	if opts.WordsMax != 0 && opts.WordsMax != opts.WordsPerCode {
+		if opts.Format != "" {
+			return nil, errorf(ErrInvalidOptions, "a range of words per code cannot be combined with a format")
+		}
+		if opts.WordsMax < opts.WordsPerCode {
+			return nil, errorf(ErrInvalidOptions, "maximum words per code (%d) must not be less than the minimum (%d)", opts.WordsMax, opts.WordsPerCode)
+		}
+		most = opts.WordsMax
+	}
+
+	var layouts []layout
+	for n := opts.WordsPerCode; n <= most; n++ {
+		opts.WordsPerCode = n
+		l, err := newLayout(opts)
+		if err != nil {
+			return nil, err
+		}
+		layouts = append(layouts, l)
+	}
+	return layouts, nil
+}
+
+// newSpace validates opts and returns the space of codes drawn from words
+func newSpace(words []string, opts Options) (space, error) {
+	layouts, err := newLayouts(opts)
+	if err != nil {
+		return space{}, err
+	}
+	if !ValidCase(opts.Case) {
+		return space{}, errorf(ErrInvalidOptions, "unknown case style %q (want %s, %s, %s or %s)", opts.Case, CaseLower, CaseUpper, CaseTitle, CaseKeep)
+	}
+
+	var sp space
+	for _, l := range layouts {
+		if len(words) < l.words {
+			return space{}, errorf(ErrInsufficientWords, "insufficient words in dictionary (need at least %d)", l.words)
+		}
+		groups := wordGroups(words, l.words, opts)
+		if len(groups) == 0 {
+			return space{}, errorf(ErrInsufficientWords, "insufficient words %s in dictionary (need at least %d)", groupConstraint(opts), l.words)
+		}
+		groups, leads := leadFirst(groups, opts)
+		if len(groups) == 0 {
+			return space{}, errorf(ErrInsufficientWords, "no suitable words starting with %q in dictionary to begin codes with", opts.FirstLetter)
+		}
+
+		// Calculate maximum possible unique combinations, summed over the
+		// groups
+		sn := span{start: len(sp.groups), end: len(sp.groups) + len(groups)}
+		for i, g := range groups {
+			size := combinationCount(len(g), leads[i], l, opts)
+			sp.layouts = append(sp.layouts, l)
+			sp.groups = append(sp.groups, g)
+			sp.leads = append(sp.leads, leads[i])
+			sp.sizes = append(sp.sizes, size)
+			sn.total = saturatingAdd(sn.total, size)
+		}
+		sp.spans = append(sp.spans, sn)
+		sp.maxWords = max(sp.maxWords, l.words)
+		sp.total = saturatingAdd(sp.total, sn.total)
+	}
+	sp.used = min(countShaped(opts.Used, layouts, opts), sp.total)
+	return sp, nil
+}
+
+// countShaped returns how many of the codes in used are shaped like codes of
+// layouts, so that codes kept from other formats, such as word codes next to
+// numeric ones, do not count against the codes left to generate
+func countShaped(used map[string]bool, layouts []layout, opts Options) int {
+	if len(used) == 0 {
+		return 0
+	}
+	patterns := make([]string, len(layouts))
+	for i, l := range layouts {
+		patterns[i] = l.pattern(digitSet(opts))
+	}
+	shape := regexp.MustCompile("^(?s:" + strings.Join(patterns, "|") + ")$")
+	n := 0
+	for code := range used {
+		if shape.MatchString(code) {
+			n++
+		}
+	}
+	return n
+}
+
+// drawer draws random codes from a space, keeping the word samplers and
+// buffers from one code to the next
+type drawer struct {
+	sp           space
+	opts         Options
+	samplers     []sampler // of the words of each group
+	leadSamplers []sampler // of the words of each group that may begin a code
+	picked       []string  // words of the code being drawn
+	indexes      []int     // their indexes in the group
+	digits       []byte
+	numbers      []int
+	digitChars   string
+}
+
+func newDrawer(sp space, opts Options) *drawer {
+	// The digits and numbers are the same for every length, which only
+	// differ in their number of words
+	d := &drawer{
+		sp:           sp,
+		opts:         opts,
+		samplers:     make([]sampler, len(sp.groups)),
+		leadSamplers: make([]sampler, len(sp.groups)),
+		picked:       make([]string, sp.maxWords),
+		indexes:      make([]int, sp.maxWords),
+		digits:       make([]byte, sp.layouts[0].digits),
+		numbers:      make([]int, len(sp.layouts[0].numbers)),
+		digitChars:   digitSet(opts),
+	}
+	for i, g := range sp.groups {
+		d.samplers[i] = newSampler(g, opts.Weights)
+		d.leadSamplers[i] = newSampler(g[:sp.leads[i]], opts.Weights)
+	}
+	return d
+}
+
+// draw returns a random code and the words picked for it, which stay valid
+// until the next draw. Each code length is equally likely, then a group is
+// chosen weighted by its number of codes of that length, so every such code
+// is equally likely, then random words from it, re-rolling repeats, counted
+// in stats, if they must be distinct.
+func (d *drawer) draw(picker wordPicker, stats *Stats) (string, []string) {
+	sp := d.sp
+	sn := sp.spans[0]
+	if len(sp.spans) > 1 {
+		sn = sp.spans[picker.Intn(len(sp.spans))]
+	}
+	g := sn.start
+	if sn.end-sn.start > 1 {
+		g, _ = pickGroup(picker.Intn(sn.total), sp.sizes[sn.start:sn.end])
+		g += sn.start
+	}
+	l, words := sp.layouts[g], sp.groups[g]
+	picked := d.picked[:l.words]
+	for i := range picked {
+		if i == 0 {
+			d.indexes[i] = d.leadSamplers[g].pick(picker)
+		} else {
+			d.indexes[i] = d.samplers[g].pick(picker)
+		}
+		for d.opts.Distinct && slices.Contains(d.indexes[:i], d.indexes[i]) {
+			d.indexes[i] = d.samplers[g].pick(picker)
+			stats.Rerolls++
+		}
+		picked[i] = words[d.indexes[i]]
+	}
+	for i := range d.digits {
+		d.digits[i] = d.digitChars[picker.Intn(len(d.digitChars))]
+	}
+	for i, n := range l.numbers {
+		d.numbers[i] = picker.Intn(n + 1)
+	}
+	return l.render(picked, string(d.digits), d.numbers, d.opts.Case), picked
+}
+
+// GenerateStream generates the same codes as Generate but passes each one to
+// out as soon as it is produced instead of collecting them, so large batches
+// can be written out incrementally. Generation stops at the first error
+// returned by out.
+func GenerateStream(words []string, count int, opts Options, out func(string) error) error {
+	return GenerateStreamContext(context.Background(), words, count, opts, out)
+}
+
+// ctxCheckInterval is how many codes, kept or re-rolled, are tried between
+// checks for cancellation
+const ctxCheckInterval = 256
+
+// GenerateStreamContext is GenerateStream, stopping early with ctx.Err() once
+// ctx is done.
+func GenerateStreamContext(ctx context.Context, words []string, count int, opts Options, out func(string) error) error {
+	sp, err := newSpace(words, opts)
+	if err != nil {
+		return err
+	}
+	groups, maxCombinations := sp.groups, sp.total
+
+	stats := opts.Stats
+	if stats == nil {
+		stats = new(Stats)
+	}
+	*stats = Stats{Combinations: maxCombinations, Words: make(map[string]int)}
+	if count > maxCombinations {
+		return errorf(ErrCountTooLarge, "requested count (%d) exceeds maximum possible combinations (%d)", count, maxCombinations)
+	}
+	if remaining := maxCombinations - sp.used; count > remaining {
+		return errorf(ErrCountTooLarge, "requested count (%d) exceeds remaining combinations (%d) after excluding %d previously used codes", count, remaining, sp.used)
+	}
+	if err := checkUniqueWords(groups, count, sp.maxWords, opts); err != nil {
+		return err
+	}
+	if opts.MinDistance < 0 {
+		return errorf(ErrInvalidOptions, "minimum distance must not be negative (got %d)", opts.MinDistance)
+	}
+
+	picker := newPicker(opts)
+	// Codes generated in this call; opts.Used is checked alongside rather
+	// than copied in, as it may be large
+	generated := make(map[string]bool, count)
+	near := newNearCodes(opts)
+
+	if opts.UniqueWords {
+		return generateUniqueWords(ctx, sp, count, opts, picker, generated, near, stats, out)
+	}
+
+	// Rejection sampling re-rolls more and more duplicates as the space fills
+	// up, so requests for most of it shuffle the whole space instead, where
+	// weights make little difference as most codes get used anyway
+	if opts.Exhaustive || count+sp.used > maxCombinations/2 {
+		return generateDense(ctx, sp, count, opts, picker, generated, near, stats, out)
+	}
+
+	d := newDrawer(sp, opts)
+	rejections := 0 // codes rejected in a row
+	for produced, tries := 0, 0; produced < count; tries++ {
+		if tries%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		code, picked := d.draw(picker, stats)
+
+		// Check for uniqueness, after casing so that words differing only by
+		// case collapse into one code
+		if generated[code] || opts.Used[code] {
+			stats.Rerolls++
+			if rejections++; rejections >= rejectionLimit(maxCombinations, len(generated)+sp.used) {
+				return errorf(ErrCountTooLarge, "gave up after %d codes in a row repeated earlier ones, so the options allow fewer unique codes than requested (%d of %d codes generated)", rejections, produced, count)
+			}
+			continue
+		}
+		if rejected(code, opts) {
+			stats.Rerolls++
+			if rejections++; rejections >= rejectionLimit(maxCombinations, len(generated)+sp.used) {
+				return errorf(ErrCountTooLarge, "gave up after %d codes in a row contained a rejected substring (%d of %d codes generated)", rejections, produced, count)
+			}
+			continue
+		}
+		if !varied(stats.Words, picked, count-produced-1, sp.maxWords, opts) {
+			stats.Rerolls++
+			if rejections++; rejections >= rejectionLimit(maxCombinations, len(generated)+sp.used) {
+				return errorf(ErrCountTooLarge, "gave up after %d codes in a row added too few new words to reach %d unique words (%d of %d codes generated)", rejections, opts.MinUniqueWords, produced, count)
+			}
+			continue
+		}
+		if mismatched(code, opts) {
+			stats.Rerolls++
+			if rejections++; rejections >= rejectionLimit(maxCombinations, len(generated)+sp.used) {
+				return errorf(ErrCountTooLarge, "gave up after %d codes in a row did not match %s (%d of %d codes generated)", rejections, opts.Match, produced, count)
+			}
+			continue
+		}
+		if near.tooClose(code) {
+			stats.Rerolls++
+			if rejections++; rejections >= rejectionLimit(maxCombinations, len(generated)+sp.used) {
+				return errorf(ErrCountTooLarge, "gave up after %d codes in a row were fewer than %d edits from an earlier one (%d of %d codes generated)", rejections, opts.MinDistance, produced, count)
+			}
+			continue
+		}
+		rejections = 0
+		generated[code] = true
+		near.add(code)
+		produced++
+		stats.add(picked)
+		if err := out(code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,642 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSaturatingMul(t *testing.T) {
+	tests := []struct {
+		a, b, want int
+	}{
+		{0, 0, 0},
+		{0, math.MaxInt, 0},
+		{math.MaxInt, 0, 0},
+		{math.MaxInt, 1, math.MaxInt},
+		{math.MaxInt / 2, 2, math.MaxInt - 1},
+		{math.MaxInt/2 + 1, 2, math.MaxInt},
+		{math.MaxInt, math.MaxInt, math.MaxInt},
+	}
+	for _, tt := range tests {
+		if got := saturatingMul(tt.a, tt.b); got != tt.want {
+			t.Errorf("saturatingMul(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCombinationCount(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		opts Options
+		want int
+	}{
+		{"three words", 10, Options{WordsPerCode: 3}, 1000},
+		{"one word", 7, Options{WordsPerCode: 1}, 7},
+		{"distinct", 10, Options{WordsPerCode: 3, Distinct: true}, 720},
+		{"distinct too few words", 2, Options{WordsPerCode: 3, Distinct: true}, 0},
+		{"digits", 10, Options{WordsPerCode: 2, Digits: 3}, 100000},
+		{"format", 10, Options{Format: "{word}-{digits:2}"}, 1000},
+		{"number", 10, Options{WordsPerCode: 2, NumberMax: 99, NumberPosition: NumberMiddle}, 10000},
+		{"format number", 10, Options{Format: "{word}{number:4}{digits:1}"}, 500},
+		{"invalid format", 10, Options{Format: "{digits:x}"}, 0},
+		// 2^(IntSize-2) is the largest power of two an int holds
+		{"largest power of two", 2, Options{WordsPerCode: strconv.IntSize - 2}, 1 << (strconv.IntSize - 2)},
+		{"one doubling past", 2, Options{WordsPerCode: strconv.IntSize - 1}, math.MaxInt},
+		{"largest with digits", 2, Options{WordsPerCode: strconv.IntSize - 5, Digits: 1}, 10 << (strconv.IntSize - 5)},
+		{"overflow", 100000, Options{WordsPerCode: 5}, math.MaxInt},
+		{"digit overflow", 2, Options{WordsPerCode: 1, Digits: 19}, math.MaxInt},
+		{"distinct overflow", 100000, Options{WordsPerCode: 5, Distinct: true}, math.MaxInt},
+	}
+	for _, tt := range tests {
+		if got := CombinationCount(tt.n, tt.opts); got != tt.want {
+			t.Errorf("%s: CombinationCount(%d) = %d, want %d", tt.name, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateReportsMaxCombinations(t *testing.T) {
+	words := []string{"apple", "tree", "lamp", "door"}
+	tests := []struct {
+		opts Options
+		max  int
+	}{
+		{Options{WordsPerCode: 2, Separator: "-"}, 16},
+		{Options{WordsPerCode: 2, Separator: "-", Distinct: true}, 12},
+		{Options{WordsPerCode: 3, Separator: "-", Distinct: true}, 24},
+		{Options{WordsPerCode: 1, Separator: "-", Digits: 1}, 40},
+	}
+	for _, tt := range tests {
+		_, err := Generate(words, tt.max+1, tt.opts)
+		want := fmt.Sprintf("exceeds maximum possible combinations (%d)", tt.max)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Generate(%d, %+v) error = %v, want %q", tt.max+1, tt.opts, err, want)
+		}
+
+		codes, err := Generate(words, tt.max, tt.opts)
+		if err != nil {
+			t.Errorf("Generate(%d, %+v) error = %v", tt.max, tt.opts, err)
+		} else if len(codes) != tt.max {
+			t.Errorf("Generate(%d, %+v) returned %d codes", tt.max, tt.opts, len(codes))
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	words := []string{"apple", "tree"}
+	tests := []struct {
+		name   string
+		words  []string
+		count  int
+		opts   Options
+		target error
+	}{
+		{"too few words", words, 1, Options{WordsPerCode: 3}, ErrInsufficientWords},
+		{"too few alliterative words", words, 1, Options{WordsPerCode: 2, Alliterative: true}, ErrInsufficientWords},
+		{"count too large", words, 5, Options{WordsPerCode: 2}, ErrCountTooLarge},
+		{"count too large after used", words, 4, Options{WordsPerCode: 2, Separator: "-", Used: map[string]bool{"tree-tree": true}}, ErrCountTooLarge},
+		{"bad case", words, 1, Options{WordsPerCode: 1, Case: "kebab"}, ErrInvalidOptions},
+		{"bad format", words, 1, Options{Format: "{nope}"}, ErrInvalidOptions},
+	}
+	for _, tt := range tests {
+		_, err := Generate(tt.words, tt.count, tt.opts)
+		if !errors.Is(err, tt.target) {
+			t.Errorf("%s: Generate error = %v, want %v", tt.name, err, tt.target)
+		}
+	}
+
+	_, err := ReadWords(t.TempDir()+"/missing", Filter{MaxLen: 10})
+	if !errors.Is(err, ErrDictNotFound) {
+		t.Errorf("ReadWords of a missing file: error = %v, want %v", err, ErrDictNotFound)
+	}
+	_, err = ReadWordsFrom(strings.NewReader("A\nB\n"), Filter{MaxLen: 10})
+	if !errors.Is(err, ErrInsufficientWords) {
+		t.Errorf("ReadWordsFrom with no valid words: error = %v, want %v", err, ErrInsufficientWords)
+	}
+}
+
+// testWords is a small dictionary for generation tests
+var testWords = []string{"apple", "tree", "lamp", "door", "cloud", "river", "stone", "bread"}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	opts := Options{WordsPerCode: 3, Separator: "-", Seed: 42}
+	first, err := Generate(testWords, 20, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Generate(testWords, 20, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(first, second) {
+		t.Errorf("same seed gave different codes:\n%v\n%v", first, second)
+	}
+
+	opts.Seed = 43
+	other, err := Generate(testWords, 20, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Equal(first, other) {
+		t.Errorf("seeds 42 and 43 gave the same codes: %v", first)
+	}
+}
+
+func TestGenerateWithRand(t *testing.T) {
+	opts := Options{WordsPerCode: 2, Separator: "-", Seed: 7}
+	want, err := Generate(testWords, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A Rand with the same seed replaces Seed, and is advanced by each call
+	opts.Seed = 0
+	opts.Rand = rand.New(rand.NewSource(7))
+	got, err := Generate(testWords, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Generate with Rand = %v, want %v", got, want)
+	}
+	next, err := Generate(testWords, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Equal(next, want) {
+		t.Errorf("Generate with an advanced Rand repeated the codes %v", next)
+	}
+}
+
+func TestGenerateContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, count := range []int{10, 500} { // sparse and dense
+		codes, err := GenerateContext(ctx, testWords, count, Options{WordsPerCode: 3, Separator: "-"})
+		if !errors.Is(err, context.Canceled) || len(codes) != 0 {
+			t.Errorf("count %d: canceled GenerateContext = %d codes, %v; want none and context.Canceled", count, len(codes), err)
+		}
+	}
+
+	// Cancelling partway keeps the codes produced so far
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var codes []string
+	err := GenerateStreamContext(ctx, testWords, 1000, Options{WordsPerCode: 3, Separator: "-", Digits: 3}, func(code string) error {
+		if codes = append(codes, code); len(codes) == 5 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateStreamContext error = %v, want context.Canceled", err)
+	}
+	if len(codes) < 5 || len(codes) >= 1000 {
+		t.Errorf("GenerateStreamContext produced %d codes after cancelling at 5 of 1000", len(codes))
+	}
+}
+
+func TestGenerateUnique(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		opts  Options
+	}{
+		{"sparse", 50, Options{WordsPerCode: 3, Separator: "-"}},
+		{"dense", 64, Options{WordsPerCode: 2, Separator: "-"}},
+		{"distinct", 56, Options{WordsPerCode: 2, Separator: "-", Distinct: true}},
+		{"digits", 80, Options{WordsPerCode: 1, Separator: "-", Digits: 1}},
+		{"alliterative", 2, Options{WordsPerCode: 1, Alliterative: true}},
+		{"exhaustive", 1000, Options{WordsPerCode: 3, Separator: "-", Digits: 4, Exhaustive: true}},
+	}
+	for _, tt := range tests {
+		tt.opts.Rand = rand.New(rand.NewSource(1))
+		codes, err := Generate(testWords, tt.count, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(codes) != tt.count {
+			t.Errorf("%s: got %d codes, want %d", tt.name, len(codes), tt.count)
+		}
+		seen := make(map[string]bool)
+		for _, code := range codes {
+			if seen[code] {
+				t.Errorf("%s: code %q generated twice", tt.name, code)
+			}
+			seen[code] = true
+			if tt.opts.Distinct {
+				parts := strings.Split(code, "-")
+				if parts[0] == parts[1] {
+					t.Errorf("%s: code %q repeats a word", tt.name, code)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateExhaustiveNeverRerolls(t *testing.T) {
+	// Large enough that the shuffle only stores the positions it moves
+	var stats Stats
+	opts := Options{WordsPerCode: 3, Separator: "-", Digits: 4, Exhaustive: true, Seed: 1, Stats: &stats}
+	if _, err := Generate(testWords, 5000, opts); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Combinations <= denseLimit {
+		t.Fatalf("test space of %d codes fits the dense shuffle", stats.Combinations)
+	}
+	if stats.Generated != 5000 || stats.Rerolls != 0 {
+		t.Errorf("exhaustive generation: %d codes, %d rerolls; want 5000 and none", stats.Generated, stats.Rerolls)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want float64
+	}{
+		{"words", Options{WordsPerCode: 3}, 9},
+		{"digits", Options{WordsPerCode: 1, Digits: 2}, 3 + 2*math.Log2(10)},
+		{"distinct", Options{WordsPerCode: 2, Distinct: true}, math.Log2(8 * 7)},
+		{"groups", Options{WordsPerCode: 1, Alliterative: true}, 3},
+		{"beyond MaxInt", Options{WordsPerCode: 8, Digits: 20}, 24 + 20*math.Log2(10)},
+	}
+	for _, tt := range tests {
+		got, err := Entropy(testWords, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Entropy = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateFirstLetter(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		opts  Options
+	}{
+		{"sparse", 5, Options{WordsPerCode: 3, Separator: "-", FirstLetter: 'T'}},
+		{"dense", 7, Options{WordsPerCode: 2, Separator: "-", Distinct: true, FirstLetter: 't'}},
+	}
+	for _, tt := range tests {
+		tt.opts.Rand = rand.New(rand.NewSource(1))
+		codes, err := Generate(testWords, tt.count, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for _, code := range codes {
+			if !strings.HasPrefix(code, "tree-") {
+				t.Errorf("%s: code %q does not start with a word beginning with t", tt.name, code)
+			}
+		}
+	}
+
+	opts := Options{WordsPerCode: 2, Distinct: true, FirstLetter: 't'}
+	if n, _ := MaxCombinations(testWords, opts); n != 7 {
+		t.Errorf("MaxCombinations = %d, want 7", n)
+	}
+	opts.FirstLetter = 'z'
+	if _, err := MaxCombinations(testWords, opts); !errors.Is(err, ErrInsufficientWords) {
+		t.Errorf("MaxCombinations without words starting with z: got %v, want ErrInsufficientWords", err)
+	}
+}
+
+func TestGenerateRejectSubstrings(t *testing.T) {
+	// "el" only appears across words once joined, as in tree-lamp, and "pp"
+	// within apple
+	opts := Options{WordsPerCode: 2, Separator: "-", RejectSubstrings: []string{"EL", "pp"}}
+	for _, count := range []int{10, 40} { // sparse and dense
+		opts.Rand = rand.New(rand.NewSource(1))
+		codes, err := Generate(testWords, count, opts)
+		if err != nil {
+			t.Fatalf("count %d: %v", count, err)
+		}
+		for _, code := range codes {
+			joined := strings.ReplaceAll(code, "-", "")
+			if strings.Contains(joined, "el") || strings.Contains(joined, "pp") {
+				t.Errorf("count %d: code %q contains a rejected substring", count, code)
+			}
+		}
+	}
+
+	opts.RejectSubstrings = []string{"a", "e", "o"} // in every word
+	if _, err := Generate(testWords, 5, opts); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("Generate with an unavoidable substring: error = %v, want ErrCountTooLarge", err)
+	}
+}
+
+func TestGenerateWordsRange(t *testing.T) {
+	opts := Options{WordsPerCode: 1, WordsMax: 3, Separator: "-", Seed: 1}
+	if got, want := CombinationCount(8, opts), 8+64+512; got != want {
+		t.Errorf("CombinationCount = %d, want %d", got, want)
+	}
+	for _, count := range []int{30, 500} { // sparse and dense
+		codes, err := Generate(testWords, count, opts)
+		if err != nil {
+			t.Fatalf("count %d: %v", count, err)
+		}
+		lengths := make(map[int]int)
+		for _, code := range codes {
+			lengths[len(strings.Split(code, "-"))]++
+		}
+		if len(lengths) != 3 || lengths[1] == 0 || lengths[2] == 0 || lengths[3] == 0 {
+			t.Errorf("count %d: codes by number of words = %v, want 1, 2 and 3 words", count, lengths)
+		}
+	}
+
+	if _, err := Generate(testWords, 1, Options{WordsPerCode: 3, WordsMax: 2}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("WordsMax below WordsPerCode: error = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
+func TestGenerateMinUniqueWords(t *testing.T) {
+	for _, count := range []int{4, 40} { // sparse and dense
+		var stats Stats
+		opts := Options{WordsPerCode: 2, Separator: "-", Seed: 1, MinUniqueWords: 8, Stats: &stats}
+		if _, err := Generate(testWords, count, opts); err != nil {
+			t.Errorf("count %d: %v", count, err)
+			continue
+		}
+		if len(stats.Words) < 8 {
+			t.Errorf("count %d: codes used %d unique words, want at least 8", count, len(stats.Words))
+		}
+	}
+
+	tests := []struct {
+		name  string
+		count int
+		min   int
+		want  error
+	}{
+		{"more than the codes hold", 3, 7, ErrInvalidOptions},
+		{"more than the dictionary", 10, 9, ErrInsufficientWords},
+		{"negative", 3, -1, ErrInvalidOptions},
+	}
+	for _, tt := range tests {
+		_, err := Generate(testWords, tt.count, Options{WordsPerCode: 2, MinUniqueWords: tt.min})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestGenerateUniqueWords(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"every word", Options{WordsPerCode: 2}},
+		{"first letter", Options{WordsPerCode: 2, FirstLetter: 'r'}},
+		{"range", Options{WordsPerCode: 1, WordsMax: 3}},
+	}
+	for _, tt := range tests {
+		count := len(testWords) / tt.opts.WordsPerCode
+		if tt.opts.FirstLetter != 0 {
+			count = 1 // only "river" begins with r
+		}
+		tt.opts.Separator, tt.opts.Seed, tt.opts.UniqueWords = "-", 1, true
+		codes, err := Generate(testWords, count, tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, code := range codes {
+			for _, word := range strings.Split(code, "-") {
+				if seen[word] {
+					t.Errorf("%s: word %q reused in %v", tt.name, word, codes)
+				}
+				seen[word] = true
+			}
+		}
+	}
+
+	_, err := Generate(testWords, 5, Options{WordsPerCode: 2, Seed: 1, UniqueWords: true})
+	if !errors.Is(err, ErrInsufficientWords) {
+		t.Errorf("error = %v, want %v", err, ErrInsufficientWords)
+	}
+}
+
+func TestNearCodesWithin(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // Levenshtein distance
+	}{
+		{"", "", 0},
+		{"lamp", "lamp", 0},
+		{"lamp", "lamb", 1},
+		{"lamp", "clamp", 1},
+		{"kitten", "sitting", 3},
+		{"tree", "", 4},
+	}
+	for _, tt := range tests {
+		a, b := []rune(tt.a), []rune(tt.b)
+		if (&nearCodes{min: tt.want}).within(a, b) || !(&nearCodes{min: tt.want + 1}).within(a, b) {
+			t.Errorf("distance(%q, %q) is not %d", tt.a, tt.b, tt.want)
+		}
+	}
+}
+
+func TestGenerateMinDistance(t *testing.T) {
+	for _, count := range []int{10, 40} { // sparse and dense
+		codes, err := Generate(testWords, count, Options{WordsPerCode: 2, Separator: "-", Seed: 1, MinDistance: 4})
+		if err != nil {
+			t.Errorf("count %d: %v", count, err)
+			continue
+		}
+		for i, a := range codes {
+			for _, b := range codes[:i] {
+				if (&nearCodes{min: 4}).within([]rune(a), []rune(b)) {
+					t.Errorf("count %d: %q and %q are fewer than 4 edits apart", count, a, b)
+				}
+			}
+		}
+	}
+
+	// No two of the single words are 6 edits apart
+	_, err := Generate(testWords, 2, Options{WordsPerCode: 1, Seed: 1, MinDistance: 6})
+	if !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("error = %v, want %v", err, ErrCountTooLarge)
+	}
+}
+
+func TestGenerateMatch(t *testing.T) {
+	match := regexp.MustCompile(`^(apple|tree)-`)
+	for _, exhaustive := range []bool{false, true} {
+		// Only 16 of the codes match
+		codes, err := Generate(testWords, 16, Options{WordsPerCode: 2, Separator: "-", Seed: 1, Match: match, Exhaustive: exhaustive})
+		if err != nil {
+			t.Errorf("exhaustive %v: %v", exhaustive, err)
+			continue
+		}
+		for _, code := range codes {
+			if !match.MatchString(code) {
+				t.Errorf("exhaustive %v: code %q does not match %s", exhaustive, code, match)
+			}
+		}
+	}
+
+	_, err := Generate(testWords, 2, Options{WordsPerCode: 2, Seed: 1, Match: regexp.MustCompile(`^$`)})
+	if !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("error = %v, want %v", err, ErrCountTooLarge)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	codes, err := Generate(testWords, 20, Options{WordsPerCode: 2, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := slices.Clone(codes), slices.Clone(codes)
+	Shuffle(first, Options{Seed: 7})
+	Shuffle(second, Options{Seed: 7})
+	if !slices.Equal(first, second) {
+		t.Errorf("same seed shuffled differently: %v and %v", first, second)
+	}
+	if slices.Equal(first, codes) {
+		t.Errorf("shuffle kept the generated order")
+	}
+	slices.Sort(first)
+	slices.Sort(codes)
+	if !slices.Equal(first, codes) {
+		t.Errorf("shuffle changed the codes: %v, want %v", first, codes)
+	}
+}
+
+func TestGenerateGivesUpOnRepeats(t *testing.T) {
+	// Casing collapses each word's four spellings into one code, leaving
+	// fewer unique codes than requested
+	var words []string
+	for _, w := range []string{"ab", "cd", "ef", "gh", "ij"} {
+		upper := strings.ToUpper(w)
+		words = append(words, w, upper, upper[:1]+w[1:], w[:1]+upper[1:])
+	}
+	codes, err := Generate(words, 8, Options{WordsPerCode: 1, Seed: 1})
+	if !errors.Is(err, ErrCountTooLarge) {
+		t.Fatalf("error = %v, want %v", err, ErrCountTooLarge)
+	}
+	if len(codes) != 5 {
+		t.Errorf("got %d codes before giving up, want all 5 unique ones: %v", len(codes), codes)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	opts := Options{WordsPerCode: 1, Seed: 3, Used: map[string]bool{"apple": true}}
+	g, err := NewGenerator(testWords, opts, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for range 50 {
+		code, err := g.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code == "apple" {
+			t.Fatalf("Next returned the used code %q", code)
+		}
+		if i := len(codes); i > 0 && slices.Contains(codes[max(i-4, 0):], code) {
+			t.Fatalf("Next repeated %q within the window: %v", code, codes[max(i-4, 0):])
+		}
+		codes = append(codes, code)
+	}
+	// Seven codes remain besides apple, so a window of four must recycle
+	// some of them
+	if len(slices.Compact(slices.Sorted(slices.Values(codes)))) != 7 {
+		t.Errorf("Next used %v, want all 7 remaining codes", codes)
+	}
+
+	if _, err := NewGenerator(testWords, opts, 7); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("NewGenerator with a window as large as the codes: error = %v, want %v", err, ErrCountTooLarge)
+	}
+}
+
+func TestGenerateSkipsUsed(t *testing.T) {
+	used := map[string]bool{"apple": true, "tree": true, "lamp": true}
+	opts := Options{WordsPerCode: 1, Used: used, Rand: rand.New(rand.NewSource(1))}
+	codes, err := Generate(testWords, len(testWords)-len(used), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if used[code] {
+			t.Errorf("generated previously used code %q", code)
+		}
+	}
+	if len(used) != 3 {
+		t.Errorf("Generate modified Used: %v", used)
+	}
+}
+
+func TestGenerateSkipsUsedOfOtherShapes(t *testing.T) {
+	// Word codes kept alongside numeric ones cannot be numeric codes, so
+	// they leave all 100 three-digit codes but the numeric ones available
+	numeric := Options{Numeric: 3, Seed: 1}
+	previous, err := Generate(testWords, 10, numeric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := make(map[string]bool)
+	for _, code := range previous {
+		used[code] = true
+	}
+	for i := range 120 {
+		used[fmt.Sprintf("%s-%s-%d", testWords[i%8], testWords[i/8%8], i)] = true
+	}
+	numeric.Used = used
+
+	remaining, excluded, err := RemainingCombinations(testWords, numeric)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 90 || excluded != 10 {
+		t.Errorf("RemainingCombinations = %d, %d, want 90, 10", remaining, excluded)
+	}
+	codes, err := Generate(testWords, 90, numeric)
+	if err != nil {
+		t.Fatalf("Generate 90 of the 90 remaining codes: %v", err)
+	}
+	for _, code := range codes {
+		if used[code] {
+			t.Errorf("generated previously used code %q", code)
+		}
+	}
+	if _, err := Generate(testWords, 91, numeric); !errors.Is(err, ErrCountTooLarge) {
+		t.Errorf("Generate 91 of the 90 remaining codes: error = %v, want %v", err, ErrCountTooLarge)
+	}
+}
+
+func TestGenerateDistribution(t *testing.T) {
+	// With single-word codes and enough rounds, every word should be picked
+	// about equally often as the first code of a batch
+	counts := make(map[string]int)
+	const rounds = 8000
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < rounds; i++ {
+		codes, err := Generate(testWords, 1, Options{WordsPerCode: 1, Rand: r})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[codes[0]]++
+	}
+	want := rounds / len(testWords)
+	for _, word := range testWords {
+		if got := counts[word]; got < want*8/10 || got > want*12/10 {
+			t.Errorf("word %q picked %d times, want about %d", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package codegen
+
+// nearCodes holds the codes generated so far for Options.MinDistance, with
+// buffers reused across comparisons
+type nearCodes struct {
+	min       int      // fewest edits allowed between two codes
+	codes     [][]rune // codes kept so far
+	prev, row []int    // rows of the edit distance table
+}
+
+func newNearCodes(opts Options) *nearCodes {
+	return &nearCodes{min: opts.MinDistance}
+}
+
+// tooClose reports whether code is fewer than nc.min edits away from any of
+// the codes kept so far
+func (nc *nearCodes) tooClose(code string) bool {
+	if nc.min <= 1 {
+		// Distinct codes are always at least one edit apart
+		return false
+	}
+	a := []rune(code)
+	for _, b := range nc.codes {
+		if nc.within(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// add keeps code to compare later codes with
+func (nc *nearCodes) add(code string) {
+	if nc.min > 1 {
+		nc.codes = append(nc.codes, []rune(code))
+	}
+}
+
+// within reports whether the Levenshtein distance between a and b, the
+// fewest insertions, deletions and substitutions turning one into the other,
+// is below nc.min. Only the diagonal band of the table that can stay below
+// nc.min is filled in, and it stops as soon as the answer is known.
+func (nc *nearCodes) within(a, b []rune) bool {
+	n := nc.min
+	if abs(len(a)-len(b)) >= n {
+		return false
+	}
+	if len(nc.prev) < len(b)+1 {
+		nc.prev, nc.row = make([]int, len(b)+1), make([]int, len(b)+1)
+	}
+	prev, row := nc.prev[:len(b)+1], nc.row[:len(b)+1]
+	for j := range prev {
+		prev[j] = min(j, n)
+	}
+	for i := 1; i <= len(a); i++ {
+		// Cells further than n-1 from the diagonal are at least n, which
+		// is all that matters about them
+		lo, hi := max(1, i-n+1), min(len(b), i+n-1)
+		row[0] = min(i, n)
+		if lo > 1 {
+			row[lo-1] = n
+		}
+		best := row[0]
+		for j := lo; j <= hi; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost, n)
+			best = min(best, row[j])
+		}
+		if hi < len(b) {
+			row[hi+1] = n
+		}
+		// Distances only grow from one row to the next
+		if best >= n {
+			return false
+		}
+		prev, row = row, prev
+	}
+	return prev[len(b)] < n
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,148 @@
+package codegen
+
+import (
+	"context"
+	"slices"
+)
+
+// generateDense passes count codes to out by shuffling the indexes of all
+// the codes in sp and decoding them in order, skipping codes already in
+// generated or opts.Used, containing a rejected substring, not matching
+// opts.Match, adding too few
+// new words to reach opts.MinUniqueWords or too close to an earlier code for
+// opts.MinDistance. Unlike rejection sampling it never
+// re-rolls, so it stays fast when count is close to the total. It stops with
+// ctx.Err() once ctx is done.
+func generateDense(ctx context.Context, sp space, count int, opts Options, picker wordPicker, generated map[string]bool, near *nearCodes, stats *Stats, out func(string) error) error {
+	total := sp.total
+	perm := newPermutation(total)
+	produced := 0
+	for i := 0; i < total && produced < count; i++ {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		// Partial Fisher-Yates shuffle: only the prefix that is used gets
+		// shuffled
+		index := perm.swap(i, i+picker.Intn(total-i))
+
+		g, index := pickGroup(index, sp.sizes)
+		code, picked := codeAt(index, sp.groups[g], sp.leads[g], sp.layouts[g], opts)
+		if generated[code] || opts.Used[code] || rejected(code, opts) || mismatched(code, opts) || !varied(stats.Words, picked, count-produced-1, sp.maxWords, opts) || near.tooClose(code) {
+			stats.Rerolls++
+			continue
+		}
+		generated[code] = true
+		near.add(code)
+		produced++
+		stats.add(picked)
+		if err := out(code); err != nil {
+			return err
+		}
+	}
+
+	if produced < count {
+		if opts.Match != nil {
+			return errorf(ErrCountTooLarge, "requested count (%d) exceeds the %d unique codes found matching %s", count, produced, opts.Match)
+		}
+		if opts.MinDistance > 1 {
+			return errorf(ErrCountTooLarge, "requested count (%d) exceeds the %d unique codes found at least %d edits apart", count, produced, opts.MinDistance)
+		}
+		if opts.MinUniqueWords > 0 {
+			return errorf(ErrCountTooLarge, "requested count (%d) exceeds the %d unique codes found that keep %d unique words within reach", count, produced, opts.MinUniqueWords)
+		}
+		if len(opts.RejectSubstrings) > 0 {
+			return errorf(ErrCountTooLarge, "requested count (%d) exceeds the %d unique codes available without rejected substrings", count, produced)
+		}
+		return errorf(ErrCountTooLarge, "requested count (%d) exceeds the %d unique codes available", count, produced)
+	}
+	return nil
+}
+
+// codeAt decodes index, in [0, combinationCount(len(words), lead, l, opts)),
+// into a code of layout l. The digits form the lowest places of the
+// mixed-radix index, then the numbers, then the first word with radix lead
+// and each other word with radix len(words), or one less per earlier word
+// when opts.Distinct is set. It also returns the words picked.
+func codeAt(index int, words []string, lead int, l layout, opts Options) (string, []string) {
+	digitChars := digitSet(opts)
+	digits := make([]byte, l.digits)
+	for i := len(digits) - 1; i >= 0; i-- {
+		digits[i] = digitChars[index%len(digitChars)]
+		index /= len(digitChars)
+	}
+	numbers := make([]int, len(l.numbers))
+	for i, n := range l.numbers {
+		numbers[i] = index % (n + 1)
+		index /= n + 1
+	}
+
+	picked := make([]string, l.words)
+	var taken []int // word indexes used so far, in ascending order
+	for i := range picked {
+		radix := len(words)
+		if i == 0 {
+			radix = lead
+		} else if opts.Distinct {
+			radix -= i
+		}
+		w := index % radix
+		index /= radix
+
+		if opts.Distinct {
+			// w counts only the words not taken yet, so skip past those
+			// that are
+			for _, t := range taken {
+				if t <= w {
+					w++
+				}
+			}
+			pos, _ := slices.BinarySearch(taken, w)
+			taken = slices.Insert(taken, pos, w)
+		}
+		picked[i] = words[w]
+	}
+
+	return l.render(picked, string(digits), numbers, opts.Case), picked
+}
+
+// permutation is a permutation of [0, n) being shuffled in place, which only
+// stores the positions moved so far when n is too large to hold them all
+type permutation struct {
+	dense []int
+	moved map[int]int
+}
+
+// denseLimit is the largest permutation stored in full
+const denseLimit = 1 << 20
+
+func newPermutation(n int) permutation {
+	if n > denseLimit {
+		return permutation{moved: make(map[int]int)}
+	}
+	p := permutation{dense: make([]int, n)}
+	for i := range p.dense {
+		p.dense[i] = i
+	}
+	return p
+}
+
+// swap exchanges positions i and j and returns the value now at i
+func (p permutation) swap(i, j int) int {
+	if p.dense != nil {
+		p.dense[i], p.dense[j] = p.dense[j], p.dense[i]
+		return p.dense[i]
+	}
+	at := func(k int) int {
+		if v, ok := p.moved[k]; ok {
+			return v
+		}
+		return k
+	}
+	vi, vj := at(i), at(j)
+	p.moved[j] = vi
+	delete(p.moved, i) // position i is never read again
+	return vj
+}
@@ -0,0 +1,33 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors matched by the errors returned from this package, for use
+// with errors.Is
+var (
+	ErrDictNotFound      = errors.New("dictionary not found")
+	ErrInsufficientWords = errors.New("insufficient words")
+	ErrCountTooLarge     = errors.New("count exceeds the possible codes")
+	ErrInvalidOptions    = errors.New("invalid options")
+)
+
+// sentinelError is an error that also matches a sentinel error, without the
+// sentinel's text showing up in its message
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+// Error returns the formatted message
+func (e *sentinelError) Error() string { return e.err.Error() }
+
+// Unwrap returns the sentinel along with anything wrapped by the message
+func (e *sentinelError) Unwrap() []error { return []error{e.sentinel, e.err} }
+
+// errorf formats an error like fmt.Errorf that also matches sentinel
+func errorf(sentinel error, format string, args ...any) error {
+	return &sentinelError{sentinel, fmt.Errorf(format, args...)}
+}
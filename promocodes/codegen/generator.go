@@ -0,0 +1,93 @@
+package codegen
+
+import "math/rand"
+
+// Generator hands out codes one at a time, for services that issue codes
+// as they are requested, never repeating any of the last few it generated
+// or those in Options.Used.
+//
+// It keeps the codes of its window in memory, so a window of K codes costs
+// K times the length of a code plus roughly 100 bytes of bookkeeping: around
+// 120 MB for a million three-word codes. To rule out repeats for good, pass
+// all codes issued so far as Options.Used instead.
+type Generator struct {
+	opts   Options
+	drawer *drawer         // draws codes from the space of codes, built once
+	picker wordPicker      // source of every code
+	total  int             // possible codes, saturating at math.MaxInt
+	shaped int             // codes of opts.Used among them, see countShaped
+	recent []string        // the last codes generated, oldest at next once full
+	next   int             // index in recent the next code is stored at
+	used   map[string]bool // opts.Used and recent together
+	stats  Stats           // re-rolls of distinct words, which nothing reads
+}
+
+// NewGenerator returns a Generator of codes drawn from words with opts,
+// which never repeats any of the last window codes it generated. Unless
+// opts.Secure or opts.Rand is set, the same words, options and window always
+// produce the same sequence of codes. opts.Stats is not filled in, and
+// opts.Exhaustive is ignored. The options that constrain a whole batch,
+// UniqueWords, MinUniqueWords and MinDistance, cannot be used.
+func NewGenerator(words []string, opts Options, window int) (*Generator, error) {
+	if window < 0 {
+		return nil, errorf(ErrInvalidOptions, "window must not be negative (got %d)", window)
+	}
+	if opts.UniqueWords || opts.MinUniqueWords != 0 || opts.MinDistance != 0 {
+		return nil, errorf(ErrInvalidOptions, "unique words, minimum unique words and minimum distance apply to whole batches, so a generator cannot use them")
+	}
+	sp, err := newSpace(words, opts)
+	if err != nil {
+		return nil, err
+	}
+	if remaining := sp.total - sp.used; window >= remaining {
+		return nil, errorf(ErrCountTooLarge, "window (%d) must be smaller than the %d codes available", window, remaining)
+	}
+
+	used := make(map[string]bool, len(opts.Used)+window)
+	for code := range opts.Used {
+		used[code] = true
+	}
+	opts.Used, opts.Stats = used, nil
+	if opts.Rand == nil && !opts.Secure {
+		// One source for every code, so they do not all start over from Seed
+		opts.Rand = rand.New(rand.NewSource(opts.Seed))
+	}
+	return &Generator{
+		opts:   opts,
+		drawer: newDrawer(sp, opts),
+		picker: newPicker(opts),
+		total:  sp.total,
+		shaped: sp.used,
+		recent: make([]string, 0, window),
+		used:   used,
+	}, nil
+}
+
+// Next generates the next code, re-rolling those in the window or
+// Options.Used, or rejected by Options.RejectSubstrings or Options.Match
+func (g *Generator) Next() (string, error) {
+	var code string
+	for rejections := 0; ; {
+		code, _ = g.drawer.draw(g.picker, &g.stats)
+		if !g.used[code] && !rejected(code, g.opts) && !mismatched(code, g.opts) {
+			break
+		}
+		if rejections++; rejections >= rejectionLimit(g.total, g.shaped+len(g.recent)) {
+			return "", errorf(ErrCountTooLarge, "gave up after %d codes in a row were recent, used or rejected ones", rejections)
+		}
+	}
+	if cap(g.recent) == 0 {
+		return code, nil
+	}
+
+	// Forget the oldest code once the window is full
+	if len(g.recent) < cap(g.recent) {
+		g.recent = append(g.recent, code)
+	} else {
+		delete(g.used, g.recent[g.next])
+		g.recent[g.next] = code
+	}
+	g.next = (g.next + 1) % cap(g.recent)
+	g.used[code] = true
+	return code, nil
+}
@@ -0,0 +1,110 @@
+package codegen
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordGroups returns the pools the words of a single code are drawn from:
+// all of words, or one pool per first letter when opts.Alliterative is set
+// and per word length when opts.EqualLength is. Pools with fewer than n
+// words, too few for a code, are dropped.
+func wordGroups(words []string, n int, opts Options) [][]string {
+	groups := [][]string{words}
+	if opts.Alliterative {
+		groups = splitGroups(groups, func(word string) int {
+			r, _ := utf8.DecodeRuneInString(word)
+			return int(unicode.ToLower(r))
+		})
+	}
+	if opts.EqualLength {
+		groups = splitGroups(groups, utf8.RuneCountInString)
+	}
+
+	kept := groups[:0]
+	for _, g := range groups {
+		if len(g) >= n {
+			kept = append(kept, g)
+		}
+	}
+	return kept
+}
+
+// leadFirst returns groups with the words that may begin a code, those
+// starting with opts.FirstLetter if it is set, moved to the front of each,
+// and how many there are in each group. Groups without any are dropped.
+func leadFirst(groups [][]string, opts Options) ([][]string, []int) {
+	leads := make([]int, 0, len(groups))
+	if opts.FirstLetter == 0 {
+		for _, g := range groups {
+			leads = append(leads, len(g))
+		}
+		return groups, leads
+	}
+
+	letter := unicode.ToLower(opts.FirstLetter)
+	var kept [][]string
+	for _, g := range groups {
+		var first, rest []string
+		for _, word := range g {
+			if r, _ := utf8.DecodeRuneInString(word); unicode.ToLower(r) == letter {
+				first = append(first, word)
+			} else {
+				rest = append(rest, word)
+			}
+		}
+		if len(first) > 0 {
+			kept = append(kept, append(first, rest...))
+			leads = append(leads, len(first))
+		}
+	}
+	return kept, leads
+}
+
+// groupConstraint describes the words that make up a pool of wordGroups,
+// for error messages
+func groupConstraint(opts Options) string {
+	switch {
+	case opts.Alliterative && opts.EqualLength:
+		return "starting with the same letter and of the same length"
+	case opts.Alliterative:
+		return "starting with the same letter"
+	case opts.EqualLength:
+		return "of the same length"
+	}
+	return ""
+}
+
+// splitGroups splits each group into the words sharing the same key, keeping
+// the groups in the order their first word appears
+func splitGroups(groups [][]string, key func(string) int) [][]string {
+	var split [][]string
+	for _, g := range groups {
+		index := make(map[int]int)
+		for _, word := range g {
+			k := key(word)
+			i, ok := index[k]
+			if !ok {
+				i = len(split)
+				index[k] = i
+				split = append(split, nil)
+			}
+			split[i] = append(split[i], word)
+		}
+	}
+	return split
+}
+
+// pickGroup returns the group that code index, in [0, sum of sizes), falls
+// into given each group's number of codes in sizes, and the index of the
+// code within that group
+func pickGroup(index int, sizes []int) (int, int) {
+	last := len(sizes) - 1
+	for i, size := range sizes[:last] {
+		if index < size {
+			return i, index
+		}
+		index -= size
+	}
+	return last, index
+}
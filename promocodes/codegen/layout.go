@@ -0,0 +1,248 @@
+package codegen
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tokenKind identifies the parts of a code layout
+type tokenKind int
+
+const (
+	literalToken tokenKind = iota // fixed text
+	wordToken                     // a random word
+	digitsToken                   // a run of random digits
+	checkToken                    // the check character of everything before it
+	luhnToken                     // the Luhn check digit of the digits before it
+	numberToken                   // a random number from 0 to a maximum
+)
+
+// token is one part of a code layout
+type token struct {
+	kind  tokenKind
+	text  string // literalToken: the fixed text
+	style string // wordToken: case style, or "" for Options.Case
+	n     int    // digitsToken: number of digits; numberToken: maximum value
+}
+
+// layout describes the shape shared by every code: where the words, digits
+// and fixed text go
+type layout struct {
+	tokens  []token
+	words   int   // number of word tokens
+	digits  int   // total number of digits
+	numbers []int // maximum value of each number token, in order
+}
+
+// newLayout returns the layout of the codes described by opts, parsed from
+// opts.Format if it is set
+func newLayout(opts Options) (layout, error) {
+	if opts.Numeric != 0 {
+		if opts.Numeric < 2 {
+			return layout{}, errorf(ErrInvalidOptions, "numeric codes need at least 2 digits, one of them the check digit (got %d)", opts.Numeric)
+		}
+		var l layout
+		l.add(token{kind: digitsToken, n: opts.Numeric - 1})
+		l.add(token{kind: luhnToken})
+		return l, nil
+	}
+	if opts.Format != "" {
+		l, err := parseFormat(opts.Format)
+		if err == nil && opts.Checksum {
+			l.add(token{kind: checkToken})
+		}
+		return l, err
+	}
+	if opts.WordsPerCode < 1 {
+		return layout{}, errorf(ErrInvalidOptions, "words per code must be at least 1 (got %d)", opts.WordsPerCode)
+	}
+	if opts.Digits < 0 {
+		return layout{}, errorf(ErrInvalidOptions, "digits must not be negative (got %d)", opts.Digits)
+	}
+	if opts.NumberMax < 0 {
+		return layout{}, errorf(ErrInvalidOptions, "number maximum must not be negative (got %d)", opts.NumberMax)
+	}
+
+	// The number goes before the word at this index, or after all of them
+	numberAt := -1
+	if opts.NumberMax > 0 {
+		switch opts.NumberPosition {
+		case NumberPrefix:
+			numberAt = 0
+		case NumberMiddle:
+			numberAt = max(opts.WordsPerCode/2, 1)
+		case "", NumberSuffix:
+			numberAt = opts.WordsPerCode
+		default:
+			return layout{}, errorf(ErrInvalidOptions, "unknown number position %q (want %s, %s or %s)", opts.NumberPosition, NumberPrefix, NumberMiddle, NumberSuffix)
+		}
+	}
+
+	var l layout
+	if opts.Prefix != "" {
+		l.addLiteral(opts.Prefix + opts.Separator)
+	}
+	for i := 0; i <= opts.WordsPerCode; i++ {
+		if i == numberAt {
+			if i > 0 {
+				l.addLiteral(opts.Separator)
+			}
+			l.add(token{kind: numberToken, n: opts.NumberMax})
+			if i == 0 {
+				l.addLiteral(opts.Separator)
+			}
+		}
+		if i == opts.WordsPerCode {
+			break
+		}
+		if i > 0 {
+			l.addLiteral(opts.Separator)
+		}
+		l.add(token{kind: wordToken})
+	}
+	if opts.Digits > 0 {
+		l.addLiteral(opts.Separator)
+		l.add(token{kind: digitsToken, n: opts.Digits})
+	}
+	if opts.Suffix != "" {
+		l.addLiteral(opts.Separator + opts.Suffix)
+	}
+	if opts.Checksum {
+		l.addLiteral(opts.Separator)
+		l.add(token{kind: checkToken})
+	}
+	return l, nil
+}
+
+// parseFormat parses a code template such as "{word}{word}-{digits:4}".
+// Everything outside of braces is copied literally; the placeholders are:
+//
+//	{word}      a random word in the Options.Case style
+//	{Word}      a random word in title case
+//	{WORD}      a random word in upper case
+//	{digits:N}  N random digits
+//	{number:N}  a random number from 0 to N
+func parseFormat(format string) (layout, error) {
+	var l layout
+	rest := format
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			l.addLiteral(rest)
+			break
+		}
+		l.addLiteral(rest[:start])
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return layout{}, errorf(ErrInvalidOptions, "invalid format %q: unclosed %q", format, rest[start:])
+		}
+		placeholder := rest[start+1 : start+end]
+		rest = rest[start+end+1:]
+
+		switch {
+		case placeholder == "word":
+			l.add(token{kind: wordToken})
+		case placeholder == "Word":
+			l.add(token{kind: wordToken, style: CaseTitle})
+		case placeholder == "WORD":
+			l.add(token{kind: wordToken, style: CaseUpper})
+		case strings.HasPrefix(placeholder, "digits:"):
+			n, err := strconv.Atoi(strings.TrimPrefix(placeholder, "digits:"))
+			if err != nil || n < 1 {
+				return layout{}, errorf(ErrInvalidOptions, "invalid format %q: {%s} needs a positive digit count", format, placeholder)
+			}
+			l.add(token{kind: digitsToken, n: n})
+		case strings.HasPrefix(placeholder, "number:"):
+			n, err := strconv.Atoi(strings.TrimPrefix(placeholder, "number:"))
+			if err != nil || n < 1 {
+				return layout{}, errorf(ErrInvalidOptions, "invalid format %q: {%s} needs a positive maximum", format, placeholder)
+			}
+			l.add(token{kind: numberToken, n: n})
+		default:
+			return layout{}, errorf(ErrInvalidOptions, "invalid format %q: unknown placeholder {%s}", format, placeholder)
+		}
+	}
+	return l, nil
+}
+
+// add appends t to the layout
+func (l *layout) add(t token) {
+	switch t.kind {
+	case wordToken:
+		l.words++
+	case digitsToken:
+		l.digits += t.n
+	case numberToken:
+		l.numbers = append(l.numbers, t.n)
+	}
+	l.tokens = append(l.tokens, t)
+}
+
+// addLiteral appends fixed text to the layout
+func (l *layout) addLiteral(text string) {
+	if text != "" {
+		l.add(token{kind: literalToken, text: text})
+	}
+}
+
+// render builds a code from l.words picked words, l.digits digits and a
+// value for each of l.numbers, casing each word in its token's style or else
+// in style
+func (l layout) render(picked []string, digits string, numbers []int, style string) string {
+	var sb strings.Builder
+	w, n := 0, 0
+	for _, t := range l.tokens {
+		switch t.kind {
+		case literalToken:
+			sb.WriteString(t.text)
+		case wordToken:
+			wordStyle := style
+			if t.style != "" {
+				wordStyle = t.style
+			}
+			sb.WriteString(applyCase(picked[w], wordStyle))
+			w++
+		case digitsToken:
+			sb.WriteString(digits[:t.n])
+			digits = digits[t.n:]
+		case numberToken:
+			sb.WriteString(strconv.Itoa(numbers[n]))
+			n++
+		case checkToken:
+			c := checkChar(sb.String())
+			if style == CaseUpper || style == CaseTitle {
+				c = strings.ToUpper(string(c))[0]
+			}
+			sb.WriteByte(c)
+		case luhnToken:
+			sb.WriteByte(luhnDigit(sb.String()))
+		}
+	}
+	return sb.String()
+}
+
+// pattern returns a regular expression matching every code of l with its
+// digits drawn from digitChars, and others of the same shape: words stand
+// for any text, and check characters are not verified
+func (l layout) pattern(digitChars string) string {
+	var sb strings.Builder
+	for _, t := range l.tokens {
+		switch t.kind {
+		case literalToken:
+			sb.WriteString(regexp.QuoteMeta(t.text))
+		case wordToken:
+			sb.WriteString(".+")
+		case digitsToken:
+			sb.WriteString("[" + digitChars + "]{" + strconv.Itoa(t.n) + "}")
+		case numberToken:
+			sb.WriteString("[0-9]+")
+		case checkToken:
+			sb.WriteString("[0-9A-Za-z]")
+		case luhnToken:
+			sb.WriteString("[0-9]")
+		}
+	}
+	return sb.String()
+}
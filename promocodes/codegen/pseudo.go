@@ -0,0 +1,60 @@
+package codegen
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Letters pseudo-words are made of
+const (
+	pseudoConsonants = "bcdfghjklmnprstvz"
+	pseudoVowels     = "aeiou"
+)
+
+// pseudoAttempts bounds the words PseudoWords invents per word asked for, as
+// duplicates and words f rejects are drawn again
+const pseudoAttempts = 10
+
+// PseudoWords invents up to n distinct pronounceable words, such as "borfin"
+// and "mazdo", accepted by f, for when no dictionary is available. Each is
+// made of alternating consonants and vowels, with the occasional pair of
+// consonants, and is between f.MinLen and f.MaxLen letters long. The same
+// seed always invents the same words.
+func PseudoWords(n int, f Filter, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	seen := make(map[string]bool, n)
+	var words []string
+	for i := 0; i < n*pseudoAttempts && len(words) < n; i++ {
+		word := pseudoWord(rng, f.MinLen+rng.Intn(f.MaxLen-f.MinLen+1))
+		if seen[word] || !f.accepts(word) {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	if f.Stats != nil {
+		f.Stats.Read += len(words)
+		f.Stats.Accepted += len(words)
+	}
+	return words
+}
+
+// pseudoWord invents a pronounceable word of length letters
+func pseudoWord(rng *rand.Rand, length int) string {
+	var sb strings.Builder
+	vowel := rng.Intn(5) == 0 // most words start with a consonant
+	run := 0                  // consonants in a row so far
+	for i := 0; i < length; i++ {
+		if vowel {
+			sb.WriteByte(pseudoVowels[rng.Intn(len(pseudoVowels))])
+			vowel, run = false, 0
+			continue
+		}
+		sb.WriteByte(pseudoConsonants[rng.Intn(len(pseudoConsonants))])
+		run++
+		// Follow a consonant with a vowel, or now and then inside the word
+		// with a second consonant
+		vowel = run == 2 || i == 0 || i >= length-2 || rng.Intn(3) > 0
+	}
+	return sb.String()
+}
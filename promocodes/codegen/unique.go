@@ -0,0 +1,199 @@
+package codegen
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// generateUniqueWords passes count codes to out, none sharing a word with
+// another, ignoring case. Each group's words are shuffled once and dealt out
+// from the end, so words are never re-rolled for having been used; the words
+// after a code's first are dealt from those that cannot begin a code while
+// there are any, keeping the rest for the first words. Codes already in
+// generated or opts.Used, containing a rejected substring, not matching
+// opts.Match or too close to an earlier code give their words back. It stops
+// with ctx.Err() once ctx is done.
+func generateUniqueWords(ctx context.Context, sp space, count int, opts Options, picker wordPicker, generated map[string]bool, near *nearCodes, stats *Stats, out func(string) error) error {
+	// The spans are ordered shortest first
+	fewest := sp.layouts[0].words
+	switch {
+	case fewest == 0:
+		return errorf(ErrInvalidOptions, "unique words need codes with words in them")
+	case opts.Exhaustive:
+		return errorf(ErrInvalidOptions, "unique words cannot be combined with exhaustive generation")
+	}
+
+	need, available := uniqueWordCounts(sp, count)
+	if need > available {
+		return errorf(ErrInsufficientWords, "insufficient words in dictionary for %d codes without reusing a word (need %d, only %d usable)", count, need, available)
+	}
+
+	// leads holds the words of each group that may begin a code, rest the
+	// others
+	leads := make([][]string, len(sp.groups))
+	rest := make([][]string, len(sp.groups))
+	for g, words := range sp.groups {
+		leads[g] = slices.Clone(words[:sp.leads[g]])
+		rest[g] = slices.Clone(words[sp.leads[g]:])
+		shuffle(leads[g], picker)
+		shuffle(rest[g], picker)
+	}
+	taken := make(map[string]bool) // lowercase words used, or held by the code being built
+	deal := func(pool *[]string) (string, bool) {
+		for len(*pool) > 0 {
+			word := (*pool)[len(*pool)-1]
+			*pool = (*pool)[:len(*pool)-1]
+			if !taken[strings.ToLower(word)] {
+				return word, true
+			}
+		}
+		return "", false
+	}
+	giveBack := func(pool *[]string, word string) {
+		*pool = append(*pool, word)
+		i, last := picker.Intn(len(*pool)), len(*pool)-1
+		(*pool)[i], (*pool)[last] = (*pool)[last], (*pool)[i]
+	}
+
+	digits := make([]byte, sp.layouts[0].digits)
+	numbers := make([]int, len(sp.layouts[0].numbers))
+	digitChars := digitSet(opts)
+	picked := make([]string, 0, sp.maxWords)
+	from := make([]*[]string, 0, sp.maxWords) // pool each picked word was dealt from
+	rejections := 0                           // codes rejected in a row
+	for produced, tries := 0, 0; produced < count; tries++ {
+		if tries%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		// Select a code length, each equally likely among those leaving
+		// enough words for the remaining codes to be as short as possible,
+		// then a group weighted by the words it may have left. The counts
+		// include words used since they were shuffled, until a failed deal
+		// clears them out.
+		g, ok := pickUnusedGroup(sp, leads, rest, available-len(taken), saturatingMul(count-produced-1, fewest), picker)
+		if !ok {
+			return errorf(ErrInsufficientWords, "not enough unused words left for another code after %d of %d codes", produced, count)
+		}
+		l := sp.layouts[g]
+		picked, from = picked[:0], from[:0]
+		for i := 0; i < l.words; i++ {
+			pool := &leads[g]
+			if i > 0 {
+				pool = &rest[g]
+			}
+			word, ok := deal(pool)
+			if !ok && i > 0 {
+				pool = &leads[g]
+				word, ok = deal(pool)
+			}
+			if !ok {
+				break
+			}
+			taken[strings.ToLower(word)] = true
+			picked, from = append(picked, word), append(from, pool)
+		}
+		release := func() {
+			for i, word := range picked {
+				delete(taken, strings.ToLower(word))
+				giveBack(from[i], word)
+			}
+		}
+		if len(picked) < l.words {
+			release()
+			continue
+		}
+		for i := range digits {
+			digits[i] = digitChars[picker.Intn(len(digitChars))]
+		}
+		for i, n := range l.numbers {
+			numbers[i] = picker.Intn(n + 1)
+		}
+
+		code := l.render(picked, string(digits), numbers, opts.Case)
+		if generated[code] || opts.Used[code] || rejected(code, opts) || mismatched(code, opts) || near.tooClose(code) {
+			release()
+			stats.Rerolls++
+			if rejections++; rejections >= maxRejections {
+				return errorf(ErrCountTooLarge, "gave up after %d codes in a row repeated earlier ones, contained a rejected substring, did not match %s or were too close to an earlier one (%d of %d codes generated)", rejections, opts.Match, produced, count)
+			}
+			continue
+		}
+		rejections = 0
+		generated[code] = true
+		near.add(code)
+		produced++
+		stats.add(picked)
+		if err := out(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UniqueWordsNeeded returns how many distinct words, ignoring case, count
+// codes drawn from words with opts need for Options.UniqueWords, one per word
+// of the shortest codes, and how many there are to draw from
+func UniqueWordsNeeded(words []string, count int, opts Options) (need, available int, err error) {
+	sp, err := newSpace(words, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	need, available = uniqueWordCounts(sp, count)
+	return need, available, nil
+}
+
+// uniqueWordCounts returns UniqueWordsNeeded for the codes of sp
+func uniqueWordCounts(sp space, count int) (need, available int) {
+	distinct := make(map[string]bool)
+	for _, g := range sp.groups {
+		for _, word := range g {
+			distinct[strings.ToLower(word)] = true
+		}
+	}
+	// The spans are ordered shortest first
+	return saturatingMul(count, sp.layouts[0].words), len(distinct)
+}
+
+// pickUnusedGroup picks the group of sp the next code is dealt from, among
+// those with a word left in leads and enough in leads and rest together,
+// and whose codes leave at least reserve of the left unused words
+func pickUnusedGroup(sp space, leads, rest [][]string, left, reserve int, picker wordPicker) (int, bool) {
+	type candidate struct {
+		groups []int
+		words  int
+	}
+	var spans []candidate
+	for _, sn := range sp.spans {
+		l := sp.layouts[sn.start]
+		if left-l.words < reserve {
+			continue
+		}
+		var c candidate
+		for g := sn.start; g < sn.end; g++ {
+			if n := len(leads[g]) + len(rest[g]); len(leads[g]) > 0 && n >= l.words {
+				c.groups = append(c.groups, g)
+				c.words += n
+			}
+		}
+		if len(c.groups) > 0 {
+			spans = append(spans, c)
+		}
+	}
+	if len(spans) == 0 {
+		return 0, false
+	}
+
+	c := spans[picker.Intn(len(spans))]
+	n := picker.Intn(c.words)
+	for _, g := range c.groups {
+		if n < len(leads[g])+len(rest[g]) {
+			return g, true
+		}
+		n -= len(leads[g]) + len(rest[g])
+	}
+	return c.groups[len(c.groups)-1], true
+}
@@ -0,0 +1,56 @@
+package codegen
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// missingWeightRatio scales the smallest given weight down to the weight of
+// words that Options.Weights leaves out, so that they still appear, rarely
+const missingWeightRatio = 0.1
+
+// sampler draws the indexes of a group of words, uniformly or in proportion
+// to their weights
+type sampler struct {
+	n   int       // number of words
+	cum []float64 // running totals of the word weights, nil for uniform
+}
+
+// newSampler returns a sampler for words, weighted by weights if it is not
+// empty. Keys of weights are lowercase words.
+func newSampler(words []string, weights map[string]float64) sampler {
+	s := sampler{n: len(words)}
+	if len(weights) == 0 {
+		return s
+	}
+
+	missing := math.Inf(1)
+	for _, w := range weights {
+		if w > 0 {
+			missing = min(missing, w*missingWeightRatio)
+		}
+	}
+	s.cum = make([]float64, len(words))
+	total := 0.0
+	for i, word := range words {
+		w, ok := weights[strings.ToLower(word)]
+		if !ok || w <= 0 {
+			w = missing
+		}
+		total += w
+		s.cum[i] = total
+	}
+	return s
+}
+
+// pick draws a word index
+func (s sampler) pick(p wordPicker) int {
+	if s.cum == nil {
+		return p.Intn(s.n)
+	}
+	total := s.cum[len(s.cum)-1]
+	u := float64(p.Intn(math.MaxInt32)) / math.MaxInt32 * total
+	i := sort.Search(len(s.cum), func(i int) bool { return s.cum[i] > u })
+	return min(i, s.n-1)
+}
@@ -0,0 +1,178 @@
+package codegen
+
+import (
+	"bufio"
+	"compress/gzip"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// embeddedWords is a fallback wordlist for systems without a dictionary. It
+// is the 3-6 letter subset of the EFF large wordlist
+// (https://www.eff.org/dice), licensed under CC BY 3.0 US.
+//
+//go:embed words.txt
+var embeddedWords string
+
+// embeddedStopwords lists common English function words, such as "the" and
+// "for", that Filter.SkipStopwords keeps out of codes
+//
+//go:embed stopwords.txt
+var embeddedStopwords string
+
+// stopwords holds embeddedStopwords as a set
+var stopwords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(embeddedStopwords) {
+		words[word] = true
+	}
+	return words
+}()
+
+// Filter selects which dictionary words may appear in codes
+type Filter struct {
+	MinLen, MaxLen int             // in characters, not bytes
+	Blocked        map[string]bool // lowercased words that must never be used
+	ExcludeLetters string          // words containing any of these letters, in any case, are skipped
+	SkipStopwords  bool            // skip common English stopwords, in any case
+	Strict         bool            // accept only words made entirely of the letters a-z
+	KeepCase       bool            // accept words starting with a capital letter, such as "iPhone" or "Paris"
+	Stats          *FilterStats    // if not nil, counts are added to it as words are read
+
+	// Category, if not empty, reads each dictionary line as a word followed
+	// by its tags, such as "apple fruit", and accepts only the words tagged
+	// with it, in any case. Without it lines are read whole.
+	Category string
+}
+
+// FilterStats counts the words read from dictionaries through a Filter
+type FilterStats struct {
+	Read     int // non-blank lines read
+	Accepted int // words accepted by the filter
+}
+
+// accepts reports whether word may be used in codes
+func (f Filter) accepts(word string) bool {
+	// Filter out too short or too long words, counting characters rather
+	// than bytes
+	if n := utf8.RuneCountInString(word); n < f.MinLen || n > f.MaxLen {
+		return false
+	}
+	// Check if first character is a lowercase letter (not a proper noun), or
+	// a letter from a script without case, or any letter with KeepCase
+	r, _ := utf8.DecodeRuneInString(word)
+	if f.KeepCase {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	} else if !unicode.IsLower(r) && !(unicode.IsLetter(r) && unicode.ToUpper(r) == r && unicode.ToLower(r) == r) {
+		return false
+	}
+	// Strict mode drops apostrophes, hyphens and mixed case too
+	if f.Strict && strings.IndexFunc(word, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0 {
+		return false
+	}
+	lower := strings.ToLower(word)
+	if f.ExcludeLetters != "" && strings.ContainsAny(lower, strings.ToLower(f.ExcludeLetters)) {
+		return false
+	}
+	if f.SkipStopwords && stopwords[lower] {
+		return false
+	}
+	return !f.Blocked[lower]
+}
+
+// gzipMagic starts every gzip stream
+const gzipMagic = "\x1f\x8b"
+
+// AmbiguousLetters are the letters easily mistaken for the digits 0 and 1,
+// or for each other: i, l and o
+const AmbiguousLetters = "ilo"
+
+// ReadWords reads the words accepted by f from the dictionary file at path,
+// which holds one word per line
+func ReadWords(path string, f Filter) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errorf(ErrDictNotFound, "failed to open dictionary file %q: %w", path, err)
+		}
+		return nil, fmt.Errorf("failed to open dictionary file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	return ReadWordsFrom(file, f)
+}
+
+// ReadEmbeddedWords returns the words accepted by f from the embedded
+// wordlist
+func ReadEmbeddedWords(f Filter) ([]string, error) {
+	return ReadWordsFrom(strings.NewReader(embeddedWords), f)
+}
+
+// ReadWordsFrom reads one word per line from r and keeps those accepted by f.
+// It lets words come from any source, such as an embedded asset or an HTTP
+// response body. Gzip-compressed input is decompressed.
+func ReadWordsFrom(r io.Reader, f Filter) ([]string, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); string(magic) == gzipMagic {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress dictionary: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+
+	var words []string
+	read := 0 // non-blank lines
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word != "" {
+			read++
+		}
+		if f.Category != "" {
+			fields := strings.Fields(word)
+			if len(fields) < 2 || !slices.ContainsFunc(fields[1:], func(tag string) bool { return strings.EqualFold(tag, f.Category) }) {
+				continue
+			}
+			word = fields[0]
+		}
+		if f.accepts(word) {
+			words = append(words, word)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading dictionary file: %w", err)
+	}
+
+	if f.Stats != nil {
+		f.Stats.Read += read
+	}
+	if read == 0 {
+		return nil, errorf(ErrInsufficientWords, "dictionary is empty or contains only blank lines")
+	}
+	if len(words) == 0 && f.Category != "" {
+		return nil, errorf(ErrInsufficientWords, "no valid words tagged %q found in dictionary", f.Category)
+	}
+	if len(words) == 0 {
+		return nil, errorf(ErrInsufficientWords, "no valid words found in dictionary")
+	}
+	if f.Stats != nil {
+		f.Stats.Accepted += len(words)
+	}
+
+	return words, nil
+}
@@ -0,0 +1,147 @@
+package codegen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFilterAccepts(t *testing.T) {
+	tests := []struct {
+		word   string
+		filter Filter
+		want   bool
+	}{
+		{"plain", Filter{MinLen: 3, MaxLen: 6}, true},
+		{"mcColl", Filter{MinLen: 3, MaxLen: 6}, true},
+		{"Alice", Filter{MinLen: 3, MaxLen: 6}, false},
+		{"ab", Filter{MinLen: 3, MaxLen: 6}, false},
+		{"plain", Filter{MinLen: 3, MaxLen: 6, Strict: true}, true},
+		{"mcColl", Filter{MinLen: 3, MaxLen: 6, Strict: true}, false},
+		{"it's", Filter{MinLen: 3, MaxLen: 6, Strict: true}, false},
+		{"well-do", Filter{MinLen: 3, MaxLen: 7, Strict: true}, false},
+		{"the", Filter{MinLen: 3, MaxLen: 6, SkipStopwords: true}, false},
+		{"lamp", Filter{MinLen: 3, MaxLen: 6, ExcludeLetters: AmbiguousLetters}, false},
+		{"tree", Filter{MinLen: 3, MaxLen: 6, Blocked: map[string]bool{"tree": true}}, false},
+		{"Alice", Filter{MinLen: 3, MaxLen: 6, KeepCase: true}, true},
+		{"iPhone", Filter{MinLen: 3, MaxLen: 6, KeepCase: true}, true},
+		{"123", Filter{MinLen: 3, MaxLen: 6, KeepCase: true}, false},
+		{"MacBook", Filter{MinLen: 3, MaxLen: 6, KeepCase: true}, false},
+
+		// Lengths count characters, not bytes
+		{"café", Filter{MinLen: 4, MaxLen: 4}, true},
+		{"éclair", Filter{MinLen: 3, MaxLen: 6}, true},
+		{"straße", Filter{MinLen: 6, MaxLen: 6}, true},
+		{"mañana", Filter{MinLen: 3, MaxLen: 6}, true},
+		{"café", Filter{MinLen: 5, MaxLen: 6}, false},
+		{"Éclair", Filter{MinLen: 3, MaxLen: 6}, false},
+		{"café", Filter{MinLen: 3, MaxLen: 6, Strict: true}, false},
+
+		// Non-Latin scripts, with and without case
+		{"слово", Filter{MinLen: 5, MaxLen: 5}, true},
+		{"Москва", Filter{MinLen: 3, MaxLen: 6}, false},
+		{"λέξη", Filter{MinLen: 4, MaxLen: 4}, true},
+		{"単語", Filter{MinLen: 2, MaxLen: 2}, true},
+		{"كلمة", Filter{MinLen: 4, MaxLen: 4}, true},
+		{"123", Filter{MinLen: 3, MaxLen: 6}, false},
+		{"-ab", Filter{MinLen: 3, MaxLen: 6}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.accepts(tt.word); got != tt.want {
+			t.Errorf("%+v accepts(%q) = %v, want %v", tt.filter, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestReadWordsFromUnicode(t *testing.T) {
+	input := "café\nÉtoile\nслово\nМосква\n単語\n  naïve  \n\nab\n"
+	words, err := ReadWordsFrom(strings.NewReader(input), Filter{MinLen: 2, MaxLen: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"café", "слово", "単語", "naïve", "ab"}
+	if !slices.Equal(words, want) {
+		t.Errorf("ReadWordsFrom = %q, want %q", words, want)
+	}
+}
+
+func TestReadWordsFromGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("apple\ntree\nlamp\n"))
+	zw.Close()
+	words, err := ReadWordsFrom(&buf, Filter{MinLen: 3, MaxLen: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"apple", "tree", "lamp"}; !slices.Equal(words, want) {
+		t.Errorf("ReadWordsFrom gzip = %q, want %q", words, want)
+	}
+}
+
+func TestReadWordsFromCategory(t *testing.T) {
+	input := "apple fruit\nriver nature\npear food Fruit\nkiwi\n"
+	words, err := ReadWordsFrom(strings.NewReader(input), Filter{MinLen: 3, MaxLen: 6, Category: "fruit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"apple", "pear"}; !slices.Equal(words, want) {
+		t.Errorf("ReadWordsFrom fruit = %q, want %q", words, want)
+	}
+
+	// Without a category lines are words as written, spaces and all
+	words, err = ReadWordsFrom(strings.NewReader("ice cream\nkiwi\n"), Filter{MinLen: 3, MaxLen: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ice cream", "kiwi"}; !slices.Equal(words, want) {
+		t.Errorf("ReadWordsFrom without a category = %q, want %q", words, want)
+	}
+
+	_, err = ReadWordsFrom(strings.NewReader(input), Filter{MinLen: 3, MaxLen: 6, Category: "animal"})
+	if !errors.Is(err, ErrInsufficientWords) {
+		t.Errorf("ReadWordsFrom with an unused category: error = %v, want %v", err, ErrInsufficientWords)
+	}
+}
+
+func TestReadWordsFromEmpty(t *testing.T) {
+	for _, input := range []string{"", "\n  \n\t\n"} {
+		stats := new(FilterStats)
+		_, err := ReadWordsFrom(strings.NewReader(input), Filter{MaxLen: 10, Stats: stats})
+		if !errors.Is(err, ErrInsufficientWords) || !strings.Contains(err.Error(), "empty") {
+			t.Errorf("ReadWordsFrom(%q): error = %v, want an empty dictionary error", input, err)
+		}
+		if stats.Read != 0 {
+			t.Errorf("ReadWordsFrom(%q): read %d words, want 0", input, stats.Read)
+		}
+	}
+
+	_, err := ReadWordsFrom(strings.NewReader("A\nB\n"), Filter{MaxLen: 10})
+	if err == nil || strings.Contains(err.Error(), "empty") {
+		t.Errorf("ReadWordsFrom with only filtered words: error = %v, want a no valid words error", err)
+	}
+}
+
+func TestPseudoWords(t *testing.T) {
+	f := Filter{MinLen: 4, MaxLen: 6, ExcludeLetters: "z"}
+	words := PseudoWords(200, f, 1)
+	if len(words) != 200 {
+		t.Fatalf("PseudoWords returned %d words, want 200", len(words))
+	}
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if n := len(word); n < 4 || n > 6 || strings.Contains(word, "z") {
+			t.Errorf("PseudoWords returned %q, which the filter rejects", word)
+		}
+		if seen[word] {
+			t.Errorf("PseudoWords returned %q twice", word)
+		}
+		seen[word] = true
+	}
+	if again := PseudoWords(200, f, 1); !slices.Equal(again, words) {
+		t.Errorf("PseudoWords with the same seed returned different words")
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"promocodes/codegen"
+)
+
+// errBadFlags is returned by parseFlags when the flag package has already
+// reported a malformed command line
+var errBadFlags = errors.New("invalid command-line flags")
+
+// config holds the settings parsed from the command line and config file
+type config struct {
+	count         int
+	wordsMin      int
+	countGiven    bool   // whether the count was given, with -count, as an argument or in the config file
+	configPath    string // config file given with -config
+	dicts         []string
+	pronounceable bool
+	blocklist     string
+	frequencies   string
+	history       string
+	uniqueAcross  []string
+	filter        codegen.Filter
+	opts          codegen.Options
+
+	output      string
+	force       bool
+	safetyLimit int
+	jsonOutput  bool
+	csvOutput   bool
+	expires     time.Duration // expiry of -json and -csv codes from now, 0 for none
+	urlTemplate string
+	plainOutput bool
+	batches     int
+	perLetter   int
+	sorted      bool
+	shuffled    bool
+	identifier  bool
+
+	noColor       bool
+	qr            bool
+	phonetic      bool
+	columns       int
+	one           bool
+	minBrightness float64
+	colorMode     string
+	colorSeed     int64
+	palette       string
+
+	verbose       bool
+	logLevel      string
+	match         string // pattern of cfg.opts.Match
+	showStats     bool
+	entropy       bool
+	dryRun        bool
+	bench         bool
+	showVersion   bool
+	previewColors bool
+}
+
+// parseFlags parses the command-line arguments (without the program name)
+// into a config. The code count may be given either with -count or as a bare
+// positional number, and flags may appear before or after it.
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+	var cfg config
+	fs := flag.NewFlagSet("promocodes", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.IntVar(&cfg.count, "count", defaultCount, "number of codes to generate (may also be given as a positional argument); the TUI starts at an options form when omitted at a terminal")
+	fs.StringVar(&cfg.opts.Separator, "separator", defaultSeparator, "string placed between the words of each code (may be empty)")
+	fs.IntVar(&cfg.opts.WordsPerCode, "words", defaultWordsPerCode, "number of words in each code")
+	fs.IntVar(&cfg.wordsMin, "words-min", 0, "fewest words in a code, for codes of mixed length up to -words-max (replaces -words)")
+	fs.IntVar(&cfg.opts.WordsMax, "words-max", 0, "most words in a code, for codes of mixed length from -words-min (or -words); each length is equally likely")
+	fs.IntVar(&cfg.opts.Numeric, "numeric", 0, "generate numbers of this many digits, ending in a Luhn check digit, instead of word codes (0 disables)")
+	fs.StringVar(&cfg.opts.Format, "format", "", "template for codes, e.g. {word}{word}-{digits:4}, using {word}, {Word}, {WORD}, {digits:N} and {number:N}; replaces -words, -separator, -digits, -number-max, -prefix and -suffix")
+	fs.BoolVar(&cfg.opts.Distinct, "distinct", false, "never repeat a word within a code")
+	fs.BoolVar(&cfg.opts.Alliterative, "alliterative", false, "make all words of a code start with the same letter, like bright-blue-bear")
+	fs.BoolVar(&cfg.opts.EqualLength, "equal-length", false, "make all words of a code the same length, like chair-plank-mango")
+	fs.IntVar(&cfg.opts.Digits, "digits", 0, "number of random digits appended to each code (0 disables)")
+	fs.IntVar(&cfg.opts.NumberMax, "number-max", 0, "add a random number from 0 to this maximum to each code (0 disables)")
+	fs.StringVar(&cfg.opts.NumberPosition, "number-position", codegen.NumberSuffix, "where -number-max places the number: prefix, middle or suffix")
+	fs.BoolVar(&cfg.opts.Checksum, "checksum", false, "append a check character (Luhn mod 36) so mistyped codes can be detected")
+	fs.BoolVar(&cfg.opts.ExcludeAmbiguous, "exclude-ambiguous", false, "avoid characters that are easily confused: the digits 0 and 1, and words containing any -ambiguous-letters")
+	fs.StringVar(&cfg.filter.ExcludeLetters, "ambiguous-letters", codegen.AmbiguousLetters, "letters that -exclude-ambiguous keeps out of words (empty to allow all words)")
+	fs.StringVar(&cfg.opts.Prefix, "prefix", "", "fixed text placed before each code, joined with the separator")
+	fs.StringVar(&cfg.opts.Suffix, "suffix", "", "fixed text placed after each code, joined with the separator")
+	fs.IntVar(&cfg.filter.MinLen, "min-len", defaultMinWordLen, "minimum length of dictionary words")
+	fs.IntVar(&cfg.filter.MaxLen, "max-len", defaultMaxWordLen, "maximum length of dictionary words")
+	fs.BoolVar(&cfg.pronounceable, "pronounceable", false, "use invented but pronounceable words, like borfin-mazdo-tulep, instead of a dictionary; -min-len and -max-len still apply")
+	fs.BoolVar(&cfg.filter.KeepCase, "keep-case", false, "also use dictionary words starting with a capital letter, such as brand names, written as in the dictionary unless -case is given")
+	fs.BoolVar(&cfg.filter.Strict, "strict-words", false, "use only dictionary words made entirely of the lowercase letters a-z, dropping apostrophes, hyphens and mixed case")
+	fs.BoolVar(&cfg.filter.SkipStopwords, "no-stopwords", false, "remove common English stopwords such as \"the\", \"and\" and \"for\" from the dictionary")
+	fs.Var((*listFlag)(&cfg.opts.RejectSubstrings), "reject-substring", "discard codes containing this `text`, ignoring case and separators; repeat or comma-separate for several")
+	fs.StringVar(&cfg.filter.Category, "category", "", "draw words only from this `tag`, reading each -dict line as a word followed by its tags, like \"apple fruit\"")
+	fs.StringVar(&cfg.blocklist, "blocklist", "", "file of words (one per line, case-insensitive) removed from the dictionary, so no code contains them")
+	fs.StringVar(&cfg.frequencies, "frequencies", "", "`file` of word weights, a word and a number per line, favoring common words; unlisted words get a tenth of the smallest weight")
+	fs.IntVar(&cfg.opts.MinUniqueWords, "min-unique-words", 0, "re-roll codes so that at least this many distinct words appear across the batch, for variety with small dictionaries (0 disables)")
+	fs.StringVar(&cfg.match, "match", "", "re-roll codes that do not match this regular `expression`, such as '^[a-z]{3,6}(-[a-z]{3,6}){2}$', for constraints no other flag covers")
+	fs.IntVar(&cfg.opts.MinDistance, "min-distance", 0, "re-roll codes fewer than this many edits (typed characters added, removed or changed) from another code of the batch, so a typo does not give a valid code; slows large batches, as every code is compared with all the others (0 disables)")
+	fs.BoolVar(&cfg.opts.UniqueWords, "unique-words", false, "never use a word twice in the whole batch, as for scratch cards; needs the count times -words distinct words")
+	fs.BoolVar(&cfg.opts.Exhaustive, "exhaustive", false, "draw codes as distinct random positions in the space of all codes, so duplicates are never re-rolled (automatic when a request covers most of the space)")
+	fs.StringVar(&cfg.opts.Case, "case", defaultCase, "letter case of the words: lower, upper, title or keep (as written in the dictionary)")
+	fs.Var((*listFlag)(&cfg.dicts), "dict", "dictionary `file` to draw words from, - for stdin, or an http(s) URL to download, possibly gzip-compressed; repeat or comma-separate to merge several (default: the word list for $LANG, "+strings.Join(systemDicts, " or ")+", else an embedded list)")
+	fs.Int64Var(&cfg.opts.Seed, "seed", 0, "seed for reproducible generation (default: time-based; cannot be combined with -secure)")
+	fs.BoolVar(&cfg.opts.Secure, "secure", false, "pick words with crypto/rand so codes cannot be predicted (cannot be combined with -seed)")
+	fs.StringVar(&cfg.history, "history", "", "file recording every generated code; codes already in it are never generated again")
+	fs.Var((*listFlag)(&cfg.uniqueAcross), "unique-across", "`file` of previously issued codes, one per line, that are never generated again; unlike -history it is not written to (repeat or comma-separate for several)")
+	fs.StringVar(&cfg.output, "output", "", "write codes to this file instead of starting the TUI")
+	fs.BoolVar(&cfg.force, "force", false, "overwrite output files that already exist, and allow counts above -max-combinations-safety")
+	fs.IntVar(&cfg.safetyLimit, "max-combinations-safety", defaultSafetyLimit, "refuse to generate more codes than this without -force, to guard against typos (0 disables)")
+	fs.IntVar(&cfg.batches, "batches", 0, "split the codes, none repeated, over this many files batch-1.txt, batch-2.txt, ... (0 disables)")
+	fs.BoolVar(&cfg.jsonOutput, "json", false, "print codes as a JSON array instead of starting the TUI")
+	fs.StringVar(&cfg.urlTemplate, "url-template", "", "print redemption URLs instead of bare codes, made from this `URL` with {code} in place of each code, escaped for the path or the query it is in, e.g. https://shop.example.com/redeem?code={code}; -json includes it with -expires, and -csv adds a url column")
+	fs.DurationVar(&cfg.expires, "expires", 0, "pair each code in -json and -csv output with an expiry time this `duration` from now, e.g. 720h (0 disables)")
+	fs.BoolVar(&cfg.csvOutput, "csv", false, "print codes as CSV rows of index and code instead of starting the TUI")
+	fs.BoolVar(&cfg.plainOutput, "plain", false, "print codes one per line instead of starting the TUI (implied when stdout is not a terminal)")
+	fs.IntVar(&cfg.perLetter, "count-per-letter", 0, "instead of a count, generate this many codes beginning with each letter from a to z, grouped by letter; letters with too few words are skipped (0 disables)")
+	fs.BoolVar(&cfg.identifier, "identifier", false, "make codes valid environment variable names, like APPLE_TREE_LAMP: upper case, joined by _, from words of the letters a-z only")
+	fs.BoolVar(&cfg.shuffled, "shuffle", false, "shuffle the codes once they are all generated, so their order is independent of how they were generated (with -seed, output is fully reproducible)")
+	fs.BoolVar(&cfg.sorted, "sort", false, "sort the codes alphabetically once they are all generated (with -seed, output is fully reproducible)")
+	fs.BoolVar(&cfg.one, "one", false, "print a single code, copy it to the clipboard and exit, without the TUI")
+	fs.IntVar(&cfg.columns, "columns", 0, "lay the codes out in this many columns in the TUI (0 uses as many as the terminal needs to show them all, if they fit side by side)")
+	fs.BoolVar(&cfg.phonetic, "phonetic", false, "print codes as plain output, each followed by its NATO phonetic spelling for reading aloud, like apple -> Alpha Papa Papa Lima Echo")
+	fs.BoolVar(&cfg.qr, "qr", false, "show a QR code of the highlighted code in the TUI, or after each code in plain output to stdout")
+	fs.BoolVar(&cfg.noColor, "no-color", false, "render codes without colors (also enabled by a non-empty NO_COLOR environment variable)")
+	fs.Float64Var(&cfg.minBrightness, "min-brightness", defaultBrightness, "minimum brightness (0-1) of code colors against the terminal background")
+	fs.BoolVar(&cfg.verbose, "verbose", false, "print dictionary and generation statistics to stderr (the same as -log-level info)")
+	fs.StringVar(&cfg.logLevel, "log-level", defaultLogLevel, "print diagnostic messages of at least this `level` to stderr: "+strings.Join(logLevelNames, ", "))
+	fs.BoolVar(&cfg.showStats, "stats", false, "print a summary of the generated codes to stderr: how many, the unique words used and the most frequent one")
+	fs.BoolVar(&cfg.entropy, "entropy", false, "print the estimated entropy of each code in bits, log2 of the possible codes, to stderr")
+	fs.BoolVar(&cfg.bench, "bench", false, fmt.Sprintf("generate %d codes, or the count given, without printing them, then report the rate and peak memory on stderr", benchCount))
+	fs.BoolVar(&cfg.dryRun, "dry-run", false, "report whether the requested count of codes can be generated, then exit without generating them")
+	fs.StringVar(&cfg.configPath, "config", "", "TOML `file` of flag defaults, such as words = 4 (default: "+configLocation()+")")
+	fs.BoolVar(&cfg.showVersion, "version", false, "print version information and exit")
+	fs.BoolVar(&cfg.previewColors, "preview-colors", false, "print swatches of the code colors given by -palette, -min-brightness, -color-mode and -color-seed with their hex values (ANSI color numbers on terminals without true color), and exit")
+	fs.Int64Var(&cfg.colorSeed, "color-seed", 0, "seed for reproducible random code colors in the TUI (default: the -seed value)")
+	fs.StringVar(&cfg.colorMode, "color-mode", colorModeRandom, "how code colors are chosen: random, or hash for a color derived from each code that stays the same across runs")
+	fs.StringVar(&cfg.palette, "palette", "", "color theme for codes: "+strings.Join(paletteNames(), ", ")+" (default: random colors)")
+
+	fs.Usage = func() { usage(fs) }
+
+	if err := parseFlagSet(fs, args); err != nil {
+		return cfg, err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	// Accept a bare count for backward compatibility, followed by more flags
+	if fs.NArg() > 0 {
+		parsed, err := strconv.Atoi(fs.Arg(0))
+		if err != nil || parsed < 1 {
+			return cfg, fmt.Errorf("invalid count argument. Must be a positive integer")
+		}
+		if err := parseFlagSet(fs, fs.Args()[1:]); err != nil {
+			return cfg, err
+		}
+		if fs.NArg() > 0 {
+			return cfg, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+		}
+		fs.Visit(func(f *flag.Flag) {
+			set[f.Name] = true
+		})
+		if set["count"] {
+			return cfg, fmt.Errorf("count given both as -count and as an argument")
+		}
+		cfg.count, set["count"] = parsed, true
+	}
+
+	// The config file fills in the flags not given on the command line
+	configPath := cfg.configPath
+	if configPath == "" {
+		configPath = defaultConfigPath()
+	}
+	if configPath != "" {
+		if err := applyConfig(fs, configPath, cfg.configPath != "", set); err != nil {
+			return cfg, err
+		}
+	}
+	cfg.countGiven = set["count"]
+
+	if !set["seed"] {
+		cfg.opts.Seed = time.Now().UnixNano()
+	}
+	if !set["color-seed"] {
+		cfg.colorSeed = cfg.opts.Seed
+	}
+	if set["words-min"] {
+		cfg.opts.WordsPerCode = cfg.wordsMin
+	}
+	if !cfg.opts.ExcludeAmbiguous {
+		cfg.filter.ExcludeLetters = ""
+	}
+	if cfg.filter.KeepCase && !set["case"] {
+		cfg.opts.Case = codegen.CaseKeep
+	}
+	if cfg.verbose && !set["log-level"] {
+		cfg.logLevel = logLevelNames[levelInfo]
+	}
+	if cfg.one && !set["count"] {
+		cfg.count = 1
+	}
+	if cfg.bench && !set["count"] {
+		cfg.count = benchCount
+	}
+	if cfg.identifier {
+		cfg.opts.Case, cfg.opts.Separator, cfg.filter.Strict = codegen.CaseUpper, "_", true
+	}
+	if cfg.match != "" {
+		var err error
+		if cfg.opts.Match, err = regexp.Compile(cfg.match); err != nil {
+			return cfg, fmt.Errorf("invalid -match value: %w", err)
+		}
+	}
+
+	return cfg, cfg.validate(set)
+}
+
+// configLocation describes where the config file is read from without -config
+func configLocation() string {
+	if path := defaultConfigPath(); path != "" {
+		return path
+	}
+	return "none"
+}
+
+// listFlag is a flag.Value collecting every value of a flag that may be
+// repeated, each of which may also be a comma-separated list
+type listFlag []string
+
+// String returns the collected values joined with commas
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds the comma-separated values in value
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// usageConfig explains the config file, printed after the flag defaults by -h
+const usageConfig = `
+Settings come from the built-in defaults, overridden by the config file,
+overridden in turn by the command line. The config file uses flag names as
+keys, for example:
+
+  separator = "_"
+  case = "upper"
+  words = 4
+  dict = ["/usr/share/dict/words", "brands.txt"]
+`
+
+// usageExamples are printed after the flag defaults by -h
+const usageExamples = `
+Examples:
+  promocodes                            choose the count, separator and case in the TUI
+  promocodes 10                         show 10 codes in the TUI
+  promocodes -one                       print one code and copy it to the clipboard
+  promocodes -count 5 -words 2 -plain   print 5 two-word codes, one per line
+  promocodes 100 -case upper -digits 4  codes like APPLE-TREE-LAMP-0427
+  promocodes 500 -secure -json -output codes.json
+`
+
+// usage prints the help text for fs
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage: %s [flags] [count]\n\n", fs.Name())
+	fmt.Fprintf(w, "Generates unique, memorable promo codes from dictionary words.\n\nFlags:\n")
+	fs.PrintDefaults()
+	fmt.Fprint(w, usageConfig)
+	fmt.Fprint(w, usageExamples)
+}
+
+// parseFlagSet parses args with fs, mapping errors other than flag.ErrHelp to
+// errBadFlags since fs has already printed them
+func parseFlagSet(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errBadFlags
+	}
+	return err
+}
+
+// wordFlags are the flags shaping word codes, which -numeric replaces
+var wordFlags = []string{"words", "words-min", "words-max", "separator", "format", "digits", "number-max", "prefix", "suffix", "checksum", "case", "dict", "pronounceable", "category", "identifier", "count-per-letter", "unique-words"}
+
+// validate checks the parsed settings for invalid values and conflicting
+// flags; set holds the names of the flags given on the command line
+func (cfg config) validate(set map[string]bool) error {
+	switch {
+	case cfg.count < 1:
+		return fmt.Errorf("invalid -count value. Must be a positive integer")
+	case cfg.safetyLimit < 0:
+		return fmt.Errorf("invalid -max-combinations-safety value. Must not be negative")
+	case set["words"] && set["words-min"]:
+		return fmt.Errorf("-words and -words-min are mutually exclusive")
+	case set["words-min"] && cfg.wordsMin < 1:
+		return fmt.Errorf("invalid -words-min value. Must be a positive integer")
+	case set["words-max"] && cfg.opts.WordsMax < 1:
+		return fmt.Errorf("invalid -words-max value. Must be a positive integer")
+	case (set["words-min"] || set["words-max"]) && cfg.opts.Format != "":
+		return fmt.Errorf("-words-min and -words-max cannot be combined with -format")
+	case cfg.opts.WordsMax > 0 && cfg.opts.WordsPerCode > cfg.opts.WordsMax:
+		return fmt.Errorf("the fewest words per code (%d) must not be greater than -words-max (%d)", cfg.opts.WordsPerCode, cfg.opts.WordsMax)
+	case cfg.opts.Numeric < 0 || cfg.opts.Numeric == 1:
+		return fmt.Errorf("invalid -numeric value. Must be at least 2")
+	case cfg.opts.Numeric > 0 && slices.ContainsFunc(wordFlags, func(name string) bool { return set[name] }):
+		i := slices.IndexFunc(wordFlags, func(name string) bool { return set[name] })
+		return fmt.Errorf("-numeric codes have no words, so it cannot be combined with -%s", wordFlags[i])
+	case cfg.opts.WordsPerCode < 1:
+		return fmt.Errorf("invalid -words value. Must be a positive integer")
+	case cfg.filter.MinLen < 1 || cfg.filter.MaxLen < 1:
+		return fmt.Errorf("invalid -min-len/-max-len values. Both must be positive integers")
+	case cfg.filter.MinLen > cfg.filter.MaxLen:
+		return fmt.Errorf("-min-len (%d) must not be greater than -max-len (%d)", cfg.filter.MinLen, cfg.filter.MaxLen)
+	case cfg.opts.Digits < 0:
+		return fmt.Errorf("invalid -digits value. Must not be negative")
+	case cfg.opts.NumberMax < 0:
+		return fmt.Errorf("invalid -number-max value. Must not be negative")
+	case cfg.opts.NumberPosition != codegen.NumberPrefix && cfg.opts.NumberPosition != codegen.NumberMiddle && cfg.opts.NumberPosition != codegen.NumberSuffix:
+		return fmt.Errorf("invalid -number-position value %q. Must be one of %s, %s or %s", cfg.opts.NumberPosition, codegen.NumberPrefix, codegen.NumberMiddle, codegen.NumberSuffix)
+	case !codegen.ValidCase(cfg.opts.Case):
+		return fmt.Errorf("invalid -case value %q. Must be one of %s, %s, %s or %s", cfg.opts.Case, codegen.CaseLower, codegen.CaseUpper, codegen.CaseTitle, codegen.CaseKeep)
+	case cfg.opts.Secure && set["seed"]:
+		return fmt.Errorf("-secure and -seed are mutually exclusive")
+	case cfg.minBrightness < 0 || cfg.minBrightness > 1:
+		return fmt.Errorf("invalid -min-brightness value. Must be between 0 and 1")
+	case cfg.colorMode != colorModeRandom && cfg.colorMode != colorModeHash:
+		return fmt.Errorf("invalid -color-mode value %q. Must be one of %s or %s", cfg.colorMode, colorModeRandom, colorModeHash)
+	case cfg.opts.Exhaustive && cfg.frequencies != "":
+		return fmt.Errorf("-exhaustive draws every code with equal chance, so it cannot be combined with -frequencies")
+	case !slices.Contains(logLevelNames, cfg.logLevel):
+		return fmt.Errorf("invalid -log-level value %q. Must be one of %s", cfg.logLevel, strings.Join(logLevelNames, ", "))
+	case cfg.sorted && cfg.shuffled:
+		return fmt.Errorf("-sort and -shuffle are mutually exclusive")
+	case cfg.opts.UniqueWords && (cfg.opts.Exhaustive || cfg.frequencies != ""):
+		return fmt.Errorf("-unique-words deals out each word once, so it cannot be combined with -exhaustive or -frequencies")
+	case cfg.pronounceable && len(cfg.dicts) > 0:
+		return fmt.Errorf("-pronounceable and -dict are mutually exclusive")
+	case cfg.pronounceable && cfg.filter.Category != "":
+		return fmt.Errorf("-pronounceable and -category are mutually exclusive")
+	case cfg.identifier && (set["case"] || set["separator"] || set["format"] || set["keep-case"]):
+		return fmt.Errorf("-identifier sets the case and separator itself, so it cannot be combined with -case, -separator, -format or -keep-case")
+	case cfg.identifier && cfg.opts.NumberMax > 0 && cfg.opts.NumberPosition == codegen.NumberPrefix:
+		return fmt.Errorf("-identifier codes must start with a letter, so -number-position cannot be %s", codegen.NumberPrefix)
+	case cfg.identifier && !identifierPattern.MatchString(cfg.opts.Prefix+"A"+cfg.opts.Suffix):
+		return fmt.Errorf("-identifier codes may only contain A-Z, 0-9 and _, starting with a letter, so -prefix and -suffix must too")
+	case cfg.filter.KeepCase && cfg.filter.Strict:
+		return fmt.Errorf("-keep-case and -strict-words are mutually exclusive")
+	case cfg.one && (set["count"] || cfg.batches > 0 || cfg.perLetter > 0 || cfg.output != "" || cfg.jsonOutput || cfg.csvOutput || cfg.qr || cfg.phonetic):
+		return fmt.Errorf("-one cannot be combined with a count, -batches, -count-per-letter, -output, -json, -csv, -qr or -phonetic")
+	case cfg.bench && (cfg.one || cfg.dryRun || cfg.output != "" || cfg.batches > 0 || cfg.perLetter > 0):
+		return fmt.Errorf("-bench discards the codes, so it cannot be combined with -one, -dry-run, -output, -batches or -count-per-letter")
+	case cfg.phonetic && (cfg.jsonOutput || cfg.csvOutput || cfg.qr):
+		return fmt.Errorf("-phonetic only applies to plain output, so it cannot be combined with -json, -csv or -qr")
+	case cfg.qr && (cfg.jsonOutput || cfg.csvOutput || cfg.output != "" || cfg.batches > 0 || cfg.perLetter > 0):
+		return fmt.Errorf("-qr only applies to the TUI and to plain output to stdout")
+	case cfg.jsonOutput && cfg.csvOutput:
+		return fmt.Errorf("-json and -csv are mutually exclusive")
+	case cfg.opts.MinUniqueWords < 0:
+		return fmt.Errorf("invalid -min-unique-words value. Must not be negative")
+	case cfg.opts.MinDistance < 0:
+		return fmt.Errorf("invalid -min-distance value. Must not be negative")
+	case cfg.columns < 0:
+		return fmt.Errorf("invalid -columns value. Must not be negative")
+	case cfg.urlTemplate != "" && !strings.Contains(cfg.urlTemplate, "{code}"):
+		return fmt.Errorf("-url-template must contain {code}, where each code goes")
+	case cfg.urlTemplate != "" && cfg.phonetic:
+		return fmt.Errorf("-url-template and -phonetic are mutually exclusive")
+	case cfg.expires < 0:
+		return fmt.Errorf("invalid -expires value. Must not be negative")
+	case cfg.batches < 0:
+		return fmt.Errorf("invalid -batches value. Must not be negative")
+	case cfg.batches > cfg.count:
+		return fmt.Errorf("-batches (%d) must not be greater than the count (%d)", cfg.batches, cfg.count)
+	case cfg.perLetter < 0:
+		return fmt.Errorf("invalid -count-per-letter value. Must not be negative")
+	case cfg.perLetter > 0 && cfg.countGiven:
+		return fmt.Errorf("-count-per-letter and a count are mutually exclusive")
+	case cfg.perLetter > 0 && cfg.batches > 0:
+		return fmt.Errorf("-count-per-letter and -batches are mutually exclusive")
+	case cfg.perLetter > 0 && cfg.dryRun:
+		return fmt.Errorf("-count-per-letter and -dry-run are mutually exclusive")
+	case cfg.perLetter > 0 && cfg.opts.UniqueWords:
+		return fmt.Errorf("-count-per-letter generates each letter separately, so it cannot be combined with -unique-words, which applies to the whole batch")
+	case cfg.perLetter > 0 && cfg.opts.MinUniqueWords > 0:
+		return fmt.Errorf("-count-per-letter generates each letter separately, so it cannot be combined with -min-unique-words, which applies to the whole batch")
+	case cfg.perLetter > 0 && cfg.opts.MinDistance > 1:
+		return fmt.Errorf("-count-per-letter generates each letter separately, so it cannot be combined with -min-distance, which applies to the whole batch")
+	case cfg.batches > 0 && cfg.output != "":
+		return fmt.Errorf("-batches and -output are mutually exclusive")
+	}
+	if _, ok := palettes[cfg.palette]; cfg.palette != "" && !ok {
+		return fmt.Errorf("unknown -palette %q. Must be one of %s", cfg.palette, strings.Join(paletteNames(), ", "))
+	}
+	return nil
+}
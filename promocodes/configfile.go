@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+)
+
+// defaultConfigPath returns the config file read when -config is not given,
+// ghouls/config.toml in the user's config directory, or "" if there is none
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ghouls", "config.toml")
+}
+
+// applyConfig sets the flags of flags not in set from the TOML config file at
+// path, whose keys are flag names such as separator = "_" or words = 4, and
+// adds them to set. A missing file is only an error if required is set.
+func applyConfig(flags *flag.FlagSet, path string, required bool, set map[string]bool) error {
+	var settings map[string]any
+	if _, err := toml.DecodeFile(path, &settings); err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !required {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file %q: %w", path, err)
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if set[name] {
+			continue // the command line takes precedence
+		}
+		if name == "config" || flags.Lookup(name) == nil {
+			return fmt.Errorf("unknown setting %q in config file %q", name, path)
+		}
+		values, ok := settings[name].([]any)
+		if !ok {
+			values = []any{settings[name]}
+		}
+		for _, value := range values {
+			text, err := configValue(value)
+			if err == nil {
+				err = flags.Set(name, text)
+			}
+			if err != nil {
+				return fmt.Errorf("invalid setting %q in config file %q: %w", name, path, err)
+			}
+		}
+		set[name] = true
+	}
+	return nil
+}
+
+// configValue returns a value decoded from a config file as a flag argument
+func configValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
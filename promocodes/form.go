@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"promocodes/codegen"
+)
+
+// Fields of the options form, in the order they are shown
+const (
+	fieldCount = iota
+	fieldSeparator
+	fieldCase
+	numFields
+)
+
+// fieldLabels are shown before each field of the options form
+var fieldLabels = [numFields]string{"count", "separator", "case"}
+
+// formModel returns a model that starts at the options form, generating
+// codes once it is submitted
+func formModel(words []string, cfg tuiConfig) model {
+	m := initialModel(nil, words, cfg)
+	m, _ = m.showForm()
+	return m
+}
+
+// showForm switches to the options form, filled in with the current
+// settings and the count field focused
+func (m model) showForm() (model, tea.Cmd) {
+	style := m.opts.Case
+	if style == "" {
+		style = codegen.CaseLower
+	}
+	values := [numFields]string{fieldSeparator: m.opts.Separator, fieldCase: style}
+	if m.codes != nil {
+		values[fieldCount] = strconv.Itoa(m.count)
+	}
+
+	m.inputs = make([]textinput.Model, numFields)
+	for i := range m.inputs {
+		input := textinput.New()
+		input.Prompt = fmt.Sprintf("%-11s", fieldLabels[i]+":")
+		input.SetValue(values[i])
+		m.inputs[i] = input
+	}
+	m.inputs[fieldCount].Placeholder = strconv.Itoa(defaultCount)
+	m.state, m.focus, m.err = stateForm, fieldCount, nil
+	return m, m.inputs[m.focus].Focus()
+}
+
+// updateForm handles keys typed at the options form: tab and the arrow keys
+// move between the fields, enter generates codes with the settings entered
+// and esc goes back to the codes, or quits if there are none yet
+func (m model) updateForm(msg tea.KeyMsg) (model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		if m.codes == nil {
+			return m, tea.Quit
+		}
+		m.state, m.err = stateResults, nil
+		return m, nil
+	case "enter":
+		return m.submitForm()
+	case "tab", "down":
+		return m.focusField((m.focus + 1) % numFields)
+	case "shift+tab", "up":
+		return m.focusField((m.focus + numFields - 1) % numFields)
+	}
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+// focusField moves the cursor to field i of the options form
+func (m model) focusField(i int) (model, tea.Cmd) {
+	m.inputs[m.focus].Blur()
+	m.focus = i
+	return m, m.inputs[m.focus].Focus()
+}
+
+// submitForm generates codes with the settings entered at the options form,
+// defaultCount of them if no count was, and shows them
+func (m model) submitForm() (model, tea.Cmd) {
+	count := defaultCount
+	if value := strings.TrimSpace(m.inputs[fieldCount].Value()); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			m.err = fmt.Errorf("invalid count. Must be a positive integer")
+			return m, nil
+		}
+		count = n
+	}
+	if m.safetyLimit > 0 && count > m.safetyLimit && !m.force {
+		m.err = fmt.Errorf("refusing to generate %d codes, more than the safety limit of %d (use -force to generate them anyway)", count, m.safetyLimit)
+		return m, nil
+	}
+	style := strings.ToLower(strings.TrimSpace(m.inputs[fieldCase].Value()))
+	if !codegen.ValidCase(style) {
+		m.err = fmt.Errorf("invalid case %q. Must be one of %s, %s, %s or %s", style, codegen.CaseLower, codegen.CaseUpper, codegen.CaseTitle, codegen.CaseKeep)
+		return m, nil
+	}
+
+	// Work on a copy so that a failed attempt keeps the previous settings
+	next := m
+	next.count, next.opts.Separator, next.opts.Case = count, m.inputs[fieldSeparator].Value(), style
+	if m.codes != nil {
+		var cmd tea.Cmd
+		next, cmd = next.regenerate()
+		if next.err != nil {
+			m.err = next.err
+			return m, nil
+		}
+		next.state = stateResults
+		return next, cmd
+	}
+
+	// The first batch uses the seed as given, like the command line does
+	codes, err := next.generate(count, next.opts)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	next.opts.Stats = nil // regenerating is not reported
+	next.setCodes(codes)
+	next.state, next.cursor, next.err = stateResults, 0, nil
+	return next, next.record(codes)
+}
+
+// formView renders the options form
+func (m model) formView() string {
+	var sb strings.Builder
+	sb.WriteString(m.wrap("Options") + "\n\n")
+	for _, input := range m.inputs {
+		sb.WriteString(input.View() + "\n")
+	}
+	back := "quit"
+	if m.codes != nil {
+		back = "go back"
+	}
+	sb.WriteString("\n" + m.wrap("(tab to change field, enter to generate, esc to "+back+")"))
+	sb.WriteString(m.footer())
+	return sb.String()
+}
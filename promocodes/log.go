@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// logLevel is how important a diagnostic message is; messages below the
+// level selected with -log-level are dropped
+type logLevel int
+
+const (
+	levelDebug logLevel = iota // details of what is read and fetched
+	levelInfo                  // how the codes were made, such as words read
+	levelWarn                  // something that may not be what was meant
+	levelError                 // why the run failed
+)
+
+// logLevelNames are the values of -log-level, indexed by logLevel
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+// defaultLogLevel keeps normal runs quiet
+const defaultLogLevel = "warn"
+
+// logPrefixes start the messages of each level, indexed by logLevel
+var logPrefixes = []string{"Debug: ", "", "Warning: ", "Error: "}
+
+// parseLogLevel returns the level named name, and whether there is one
+func parseLogLevel(name string) (logLevel, bool) {
+	i := slices.Index(logLevelNames, name)
+	return logLevel(i), i >= 0
+}
+
+// logger writes diagnostic messages of at least its level to w, one per
+// line, keeping them apart from the codes on stdout
+type logger struct {
+	w     io.Writer
+	level logLevel
+}
+
+// diag is where all diagnostic messages go
+var diag = &logger{w: os.Stderr, level: levelWarn}
+
+// enabled reports whether messages of level are written
+func (l *logger) enabled(level logLevel) bool {
+	return level >= l.level
+}
+
+// logf writes a message of level, formatted as by fmt.Sprintf, if enabled
+func (l *logger) logf(level logLevel, format string, args ...any) {
+	if l.enabled(level) {
+		fmt.Fprintf(l.w, logPrefixes[level]+format+"\n", args...)
+	}
+}
+
+func (l *logger) debugf(format string, args ...any) { l.logf(levelDebug, format, args...) }
+func (l *logger) infof(format string, args ...any)  { l.logf(levelInfo, format, args...) }
+func (l *logger) warnf(format string, args ...any)  { l.logf(levelWarn, format, args...) }
+func (l *logger) errorf(format string, args ...any) { l.logf(levelError, format, args...) }
@@ -2,174 +2,904 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
+	"math"
+	"net/http"
 	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+	"github.com/muesli/termenv"
+
+	"promocodes/codegen"
 )
 
 const (
-	defaultCount = 3
-	minWordLen   = 3
-	maxWordLen   = 6
-	dictPath     = "/usr/share/dict/words"
+	defaultCount        = 3
+	defaultWordsPerCode = 3
+	defaultSeparator    = "-"
+	defaultCase         = codegen.CaseLower
+	defaultBrightness   = 0.2
+	maxColorRolls       = 1000 // give up on the brightness floor after this many tries
+	previewSwatches     = 24   // random colors shown by -preview-colors without a palette
+	previewColumns      = 4    // swatches per row of -preview-colors
+	defaultMinWordLen   = 3
+	defaultMaxWordLen   = 6
+	defaultSafetyLimit  = 1_000_000        // largest count generated without -force
+	pseudoWordCount     = 5000             // words invented for -pronounceable
+	dictTimeout         = 30 * time.Second // limit on downloading a -dict URL
+	exitInterrupted     = 130              // exit status after Ctrl+C, as shells use
+	benchCount          = 100_000          // codes generated by -bench without a count
+	benchSampleInterval = 1024             // codes between -bench memory samples
 )
 
-// model represents the application state
-type model struct {
-	codes []string
+// systemDicts are the usual locations of the system word list, in the order
+// they are tried when no -dict is given
+var systemDicts = []string{"/usr/share/dict/words", "/usr/dict/words"}
+
+// localeDicts maps language codes to the names of the word lists that
+// distributions install for them under /usr/share/dict
+var localeDicts = map[string]string{
+	"da": "danish",
+	"de": "ngerman",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"it": "italian",
+	"nl": "dutch",
+	"pl": "polish",
+	"pt": "portuguese",
+	"sv": "swedish",
 }
 
-// initialModel returns the initial model
-func initialModel(codes []string) model {
-	return model{
-		codes: codes,
+// findDict returns the first existing dictionary for the user's language,
+// taken from LC_ALL or LANG, or of systemDicts. It returns "" if there is
+// none.
+func findDict() string {
+	candidates := systemDicts
+	locale := cmp.Or(os.Getenv("LC_ALL"), os.Getenv("LANG"))
+	lang, _, _ := strings.Cut(locale, "_")
+	lang, _, _ = strings.Cut(lang, ".")
+	if name, ok := localeDicts[strings.ToLower(lang)]; ok {
+		candidates = append([]string{"/usr/share/dict/" + name}, candidates...)
 	}
-}
 
-// Init is called when the program starts
-func (m model) Init() tea.Cmd {
-	return nil
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
 }
 
-// Update handles messages
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
-			return m, tea.Quit
+// readWords reads the words accepted by filter from each of the dictionary
+// files at paths, merged in order, or from the embedded wordlist if paths is
+// empty. Words listed more than once, which would be picked more often, are
+// kept only where they first appear. A dictionary without any accepted words
+// only fails if none of the others has any either.
+func readWords(paths []string, filter codegen.Filter) ([]string, error) {
+	if len(paths) == 0 {
+		words, err := codegen.ReadEmbeddedWords(filter)
+		return uniqueWords(words), err
+	}
+	var words []string
+	var empty error // why the last dictionary without accepted words had none
+	for _, path := range paths {
+		dictWords, err := readDict(path, filter)
+		if errors.Is(err, codegen.ErrInsufficientWords) {
+			empty = err
+			continue
+		}
+		if err != nil {
+			return nil, err
 		}
+		words = append(words, dictWords...)
+	}
+	if len(words) == 0 {
+		return nil, empty
 	}
-	return m, nil
+	return uniqueWords(words), nil
 }
 
-// View renders the UI
-func (m model) View() string {
-	var sb strings.Builder
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	for i, code := range m.codes {
-		// Generate a random color for each code
-		color := randomColor(rng)
-		style := lipgloss.NewStyle().Foreground(color)
-		sb.WriteString(style.Render(code))
-		if i < len(m.codes)-1 {
-			sb.WriteString("\n")
+// uniqueWords removes the repeats of words from it in place, ignoring case
+// as codegen does, keeping the first of each
+func uniqueWords(words []string) []string {
+	seen := make(map[string]bool, len(words))
+	unique := words[:0]
+	for _, word := range words {
+		if lower := strings.ToLower(word); !seen[lower] {
+			seen[lower] = true
+			unique = append(unique, word)
 		}
 	}
-	return sb.String()
+	return unique
+}
+
+// readDict reads the words accepted by filter from the dictionary file at
+// path, from stdin if path is "-", or over HTTP if it is a URL
+func readDict(path string, filter codegen.Filter) ([]string, error) {
+	if path == "-" {
+		return codegen.ReadWordsFrom(os.Stdin, filter)
+	}
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return fetchDict(path, filter)
+	}
+	diag.debugf("reading dictionary %s", path)
+	return codegen.ReadWords(path, filter)
 }
 
-// randomColor generates a random color
-func randomColor(rng *rand.Rand) lipgloss.Color {
-	r := rng.Intn(256)
-	g := rng.Intn(256)
-	b := rng.Intn(256)
-	return lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", r, g, b))
+// fetchDict downloads the dictionary at url, giving up after dictTimeout, and
+// reads the words accepted by filter from it
+func fetchDict(url string, filter codegen.Filter) ([]string, error) {
+	diag.debugf("fetching dictionary %s", url)
+	client := &http.Client{Timeout: dictTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dictionary: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch dictionary %q: server responded %s", url, resp.Status)
+	}
+	return codegen.ReadWordsFrom(resp.Body, filter)
 }
 
-// readWords reads and filters words from the dictionary file
-func readWords() ([]string, error) {
-	file, err := os.Open(dictPath)
+// readBlocklist reads the words listed in the file at path, one per line, and
+// returns them lowercased for case-insensitive matching
+func readBlocklist(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open dictionary file: %w", err)
+		return nil, fmt.Errorf("failed to open blocklist file %q: %w", path, err)
 	}
 	defer file.Close()
 
-	var words []string
+	blocked := make(map[string]bool)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		word := strings.TrimSpace(scanner.Text())
-		// Filter out proper nouns (capitalized), too short, or too long words
-		if len(word) >= minWordLen && len(word) <= maxWordLen {
-			// Check if first character is lowercase (not a proper noun)
-			if len(word) > 0 && word[0] >= 'a' && word[0] <= 'z' {
-				words = append(words, word)
-			}
+		if word := strings.TrimSpace(scanner.Text()); word != "" {
+			blocked[strings.ToLower(word)] = true
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading blocklist file %q: %w", path, err)
+	}
+
+	return blocked, nil
+}
+
+// explainFiltered adds to an ErrInsufficientWords error how many of the read
+// dictionary words the filters kept, since a large dictionary can still be
+// filtered down to too few words
+func explainFiltered(err error, cfg config, read, kept int) error {
+	if !errors.Is(err, codegen.ErrInsufficientWords) || read <= kept {
+		return err
+	}
+	source := "the dictionary"
+	if len(cfg.dicts) > 1 {
+		source = fmt.Sprintf("the %d dictionaries", len(cfg.dicts))
+	}
+	if cfg.filter.Category != "" {
+		return fmt.Errorf("%w: %s had %d words but only %d passed the length/case filters and -category %q (-min-len %d, -max-len %d); try relaxing them",
+			err, source, read, kept, cfg.filter.Category, cfg.filter.MinLen, cfg.filter.MaxLen)
+	}
+	return fmt.Errorf("%w: %s had %d words but only %d passed the length/case filters (-min-len %d, -max-len %d); try relaxing them",
+		err, source, read, kept, cfg.filter.MinLen, cfg.filter.MaxLen)
+}
+
+// readFrequencies reads word weights from the file at path, one word and
+// positive weight per line separated by whitespace, such as "apple 120".
+// Words are lowercased; blank lines and lines starting with # are skipped.
+func readFrequencies(path string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frequencies file %q: %w", path, err)
+	}
+	defer file.Close()
 
+	weights := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want a word and a weight, got %q", path, line, text)
+		}
+		weight, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || weight <= 0 || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("%s:%d: invalid weight %q. Must be a positive number", path, line, fields[1])
+		}
+		weights[strings.ToLower(fields[0])] = weight
+	}
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading dictionary file: %w", err)
+		return nil, fmt.Errorf("error reading frequencies file %q: %w", path, err)
 	}
 
-	if len(words) == 0 {
-		return nil, fmt.Errorf("no valid words found in dictionary")
+	return weights, nil
+}
+
+// identifierPattern matches the codes of -identifier, which are valid
+// environment variable names
+var identifierPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// checkCode returns an error if code breaks a guarantee of cfg: with
+// -identifier, that it matches identifierPattern
+func checkCode(cfg config, code string) error {
+	if cfg.identifier && !identifierPattern.MatchString(code) {
+		return fmt.Errorf("code %q is not a valid identifier", code)
+	}
+	return nil
+}
+
+// generateCodes generates the codes for the TUI, reporting progress for large
+// batches
+func generateCodes(ctx context.Context, cfg config, words []string) ([]string, error) {
+	codes := make([]string, 0, cfg.count)
+	prog := newProgress(progressOutput(), cfg.count)
+	err := codegen.GenerateStreamContext(ctx, words, cfg.count, cfg.opts, func(code string) error {
+		if err := checkCode(cfg, code); err != nil {
+			return err
+		}
+		codes = append(codes, code)
+		prog.update(len(codes))
+		return nil
+	})
+	prog.done()
+	return codes, err
+}
+
+// progressOutput returns where generation progress is reported: stderr if it
+// is a terminal, otherwise nowhere
+func progressOutput() io.Writer {
+	if isatty.IsTerminal(os.Stderr.Fd()) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// streamCodes writes codes in format to stdout, or to the -output file, as
+// they are generated, recording each in the history file if one is set. It
+// returns the number of codes written.
+func streamCodes(ctx context.Context, cfg config, words []string, format codeFormat) (int, error) {
+	return withOutput(cfg, func(dest io.Writer) (int, error) {
+		return writeGenerated(ctx, cfg, words, format(dest))
+	})
+}
+
+// withOutput calls write with stdout, or the -output file, and flushes or
+// closes it afterwards, returning what write returns
+func withOutput(cfg config, write func(dest io.Writer) (int, error)) (int, error) {
+	dest := bufferedFile{Writer: bufio.NewWriter(os.Stdout)}
+	if cfg.output != "" {
+		var err error
+		if dest, err = createOutput(cfg.output, cfg.force); err != nil {
+			return 0, err
+		}
 	}
 
-	return words, nil
+	n, err := write(dest)
+	if dest.file != nil {
+		err = errors.Join(err, dest.Close())
+	} else {
+		err = errors.Join(err, dest.Flush())
+	}
+	return n, err
 }
 
-// generatePromoCodes generates unique promo codes
-func generatePromoCodes(words []string, count int) ([]string, error) {
-	if len(words) < 3 {
-		return nil, fmt.Errorf("insufficient words in dictionary (need at least 3)")
+// writeGenerated passes codes to out as they are generated, or all at once in
+// alphabetical order with -sort or shuffled with -shuffle, recording each in
+// the history file if one is set, then closes out. It returns the number of
+// codes written.
+func writeGenerated(ctx context.Context, cfg config, words []string, out codeWriter) (int, error) {
+	var history codeWriter
+	var historyFile bufferedFile
+	if cfg.history != "" {
+		var err error
+		if historyFile, err = openHistory(cfg.history); err != nil {
+			return 0, err
+		}
+		history = newPlainWriter(historyFile)
+	}
+
+	emit := out.WriteCode
+	var held []string
+	if cfg.sorted || cfg.shuffled {
+		// Sorting and shuffling need every code, so hold them back until the
+		// end
+		emit = func(code string) error {
+			held = append(held, code)
+			return nil
+		}
+	}
+
+	n := 0
+	prog := newProgress(progressOutput(), cfg.count)
+	err := codegen.GenerateStreamContext(ctx, words, cfg.count, cfg.opts, func(code string) error {
+		if err := checkCode(cfg, code); err != nil {
+			return err
+		}
+		if history != nil {
+			if err := history.WriteCode(code); err != nil {
+				return err
+			}
+		}
+		n++
+		prog.update(n)
+		return emit(code)
+	})
+	prog.done()
+
+	if cfg.sorted {
+		slices.Sort(held)
+	} else {
+		codegen.Shuffle(held, cfg.opts)
+	}
+	for _, code := range held {
+		if werr := out.WriteCode(code); werr != nil {
+			err = errors.Join(err, werr)
+			break
+		}
 	}
 
-	// Calculate maximum possible unique combinations
-	maxCombinations := len(words) * len(words) * len(words)
-	if count > maxCombinations {
-		return nil, fmt.Errorf("requested count (%d) exceeds maximum possible combinations (%d)", count, maxCombinations)
+	// Finish both outputs even after an error, so that every code written
+	// is also recorded in the history
+	err = errors.Join(err, out.Close())
+	if history != nil {
+		err = errors.Join(err, historyFile.Close())
 	}
+	return n, err
+}
 
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	generated := make(map[string]bool)
-	codes := make([]string, 0, count)
+// perLetterAlphabet holds the letters -count-per-letter begins codes with
+const perLetterAlphabet = "abcdefghijklmnopqrstuvwxyz"
 
-	for len(codes) < count {
-		// Select 3 random words
-		w1 := words[rng.Intn(len(words))]
-		w2 := words[rng.Intn(len(words))]
-		w3 := words[rng.Intn(len(words))]
+// writePerLetter generates cfg.perLetter codes beginning with a word starting
+// with each letter of perLetterAlphabet, skipping letters with too few words,
+// and writes them in format to stdout, or to the -output file, grouped by
+// letter. Plain output gets a heading per letter. Each group is recorded in
+// the history file if one is set. It returns the number of codes written.
+func writePerLetter(ctx context.Context, cfg config, words []string, format codeFormat) (int, error) {
+	return withOutput(cfg, func(dest io.Writer) (int, error) {
+		labels := !cfg.jsonOutput && !cfg.csvOutput
+		out := format(dest)
+		total := cfg.opts.Stats
+		var stats codegen.Stats
+		opts := cfg.opts
+		opts.Stats = &stats
 
-		code := fmt.Sprintf("%s-%s-%s", w1, w2, w3)
+		n := 0
+		var skipped []string
+		var err error
+		for i, letter := range perLetterAlphabet {
+			// Vary the seed so that letters do not share the rest of their
+			// codes
+			opts.FirstLetter, opts.Seed = letter, cfg.opts.Seed+int64(i)
+			var remaining int
+			remaining, _, err = codegen.RemainingCombinations(words, opts)
+			if err != nil && !errors.Is(err, codegen.ErrInsufficientWords) {
+				break
+			}
+			if err != nil || remaining < cfg.perLetter {
+				skipped = append(skipped, string(unicode.ToUpper(letter)))
+				err = nil
+				continue
+			}
 
-		// Check for uniqueness
-		if !generated[code] {
-			generated[code] = true
-			codes = append(codes, code)
+			var codes []string
+			err = codegen.GenerateStreamContext(ctx, words, cfg.perLetter, opts, func(code string) error {
+				codes = append(codes, code)
+				return checkCode(cfg, code)
+			})
+			// An interrupted letter still writes the codes it got
+			interrupted := errors.Is(err, context.Canceled)
+			if err != nil && !interrupted {
+				break
+			}
+			if total != nil {
+				addStats(total, stats)
+			}
+			if cfg.sorted {
+				slices.Sort(codes)
+			} else if cfg.shuffled {
+				codegen.Shuffle(codes, opts)
+			}
+			if cfg.history != "" {
+				if err = appendCodes(cfg.history, codes); err != nil {
+					break
+				}
+			}
+			if labels {
+				heading := fmt.Sprintf("%c:\n", unicode.ToUpper(letter))
+				if n > 0 {
+					heading = "\n" + heading
+				}
+				if _, err = io.WriteString(dest, heading); err != nil {
+					err = fmt.Errorf("failed to write output: %w", err)
+					break
+				}
+			}
+			for _, code := range codes {
+				if err = out.WriteCode(code); err != nil {
+					break
+				}
+				n++
+			}
+			if err != nil {
+				break
+			}
+			if interrupted {
+				err = context.Canceled
+				break
+			}
 		}
+		if len(skipped) > 0 {
+			diag.warnf("skipped letters with too few words: %s", strings.Join(skipped, ", "))
+		}
+		return n, errors.Join(err, out.Close())
+	})
+}
+
+// addStats adds the statistics of another generation run to total
+func addStats(total *codegen.Stats, stats codegen.Stats) {
+	total.Combinations = min(total.Combinations, math.MaxInt-stats.Combinations) + stats.Combinations
+	total.Generated += stats.Generated
+	total.Rerolls += stats.Rerolls
+	if total.Words == nil {
+		total.Words = make(map[string]int)
+	}
+	for word, count := range stats.Words {
+		total.Words[word] += count
 	}
+}
 
-	return codes, nil
+// exitIfInterrupted exits with exitInterrupted, saying how many codes were
+// generated, if err reports that generation was interrupted with Ctrl+C
+func exitIfInterrupted(err error, n int) {
+	if errors.Is(err, context.Canceled) {
+		diag.warnf("interrupted after %d codes", n)
+		os.Exit(exitInterrupted)
+	}
+}
+
+// dryRun writes the usable word count and combination ceiling to w, and
+// reports whether cfg.count codes can be generated from words without
+// generating them
+func dryRun(w io.Writer, cfg config, words []string) (bool, error) {
+	total, err := codegen.MaxCombinations(words, cfg.opts)
+	if err != nil {
+		return false, err
+	}
+	if cfg.opts.Numeric == 0 {
+		fmt.Fprintf(w, "Usable words: %d\n", len(words))
+	}
+	fmt.Fprintf(w, "Maximum combinations: %d\n", total)
+	remaining, excluded, err := codegen.RemainingCombinations(words, cfg.opts)
+	if err != nil {
+		return false, err
+	}
+	if len(cfg.opts.Used) > 0 {
+		fmt.Fprintf(w, "Remaining after %d previously used codes: %d\n", excluded, remaining)
+	}
+	feasible := cfg.count <= remaining
+	if cfg.opts.UniqueWords {
+		need, available, err := codegen.UniqueWordsNeeded(words, cfg.count, cfg.opts)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(w, "Words needed without reuse: %d of %d distinct\n", need, available)
+		feasible = feasible && need <= available
+	}
+	if feasible {
+		fmt.Fprintf(w, "Requested count: %d (feasible)\n", cfg.count)
+	} else {
+		fmt.Fprintf(w, "Requested count: %d (not feasible)\n", cfg.count)
+	}
+	return feasible, nil
+}
+
+// benchmark generates cfg.count codes from words without keeping them, then
+// writes the time taken, the rate and the peak heap size to w. Stopping ctx
+// ends it early, reporting the codes generated so far.
+func benchmark(ctx context.Context, w io.Writer, cfg config, words []string) error {
+	var mem runtime.MemStats
+	var peak uint64
+	sample := func() {
+		runtime.ReadMemStats(&mem)
+		peak = max(peak, mem.HeapAlloc)
+	}
+	runtime.GC()
+	sample()
+
+	n := 0
+	start := time.Now()
+	err := codegen.GenerateStreamContext(ctx, words, cfg.count, cfg.opts, func(code string) error {
+		if err := checkCode(cfg, code); err != nil {
+			return err
+		}
+		n++
+		if n%benchSampleInterval == 0 {
+			sample()
+		}
+		return nil
+	})
+	elapsed := time.Since(start)
+	sample()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	fmt.Fprintf(w, "Generated %d codes in %v (%.0f codes/s)\n", n, elapsed.Round(time.Millisecond), float64(n)/elapsed.Seconds())
+	fmt.Fprintf(w, "Peak heap: %.1f MiB\n", float64(peak)/(1<<20))
+	return nil
+}
+
+// printStats reports the statistics of a generation run on stderr: the
+// combinations and re-rolls at the info log level, and the word usage for
+// -stats
+func printStats(cfg config, stats codegen.Stats) {
+	diag.infof("Maximum combinations: %d, re-rolls: %d", stats.Combinations, stats.Rerolls)
+	if !cfg.showStats {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Generated %d codes using %d unique words", stats.Generated, len(stats.Words))
+	top, topCount := "", 0
+	for word, n := range stats.Words {
+		if n > topCount || n == topCount && word < top {
+			top, topCount = word, n
+		}
+	}
+	if topCount > 0 {
+		fmt.Fprintf(os.Stderr, "; most frequent: %q (%d times)", top, topCount)
+	}
+	fmt.Fprintln(os.Stderr)
 }
 
 func main() {
 	// Parse command-line arguments
-	count := defaultCount
-	if len(os.Args) > 1 {
-		parsed, err := strconv.Atoi(os.Args[1])
-		if err != nil || parsed < 1 {
-			fmt.Fprintf(os.Stderr, "Error: invalid count argument. Must be a positive integer.\n")
+	cfg, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if errors.Is(err, errBadFlags) {
+		os.Exit(2)
+	}
+	if err != nil {
+		diag.errorf("%v", err)
+		os.Exit(1)
+	}
+	diag.level, _ = parseLogLevel(cfg.logLevel)
+	if cfg.showVersion {
+		fmt.Println(versionString())
+		return
+	}
+	// Colors are downsampled to what the terminal can show, and left out
+	// entirely on terminals that show none
+	profile := lipgloss.ColorProfile()
+	noColor := cfg.noColor || os.Getenv("NO_COLOR") != "" || profile == termenv.Ascii
+	if cfg.previewColors {
+		err := previewColors(os.Stdout, tuiConfig{
+			noColor:        noColor,
+			colorMode:      cfg.colorMode,
+			colorSeed:      cfg.colorSeed,
+			palette:        palettes[cfg.palette],
+			minBrightness:  cfg.minBrightness,
+			darkBackground: lipgloss.HasDarkBackground(),
+			profile:        profile,
+		})
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	requested := cfg.count
+	if cfg.perLetter > 0 {
+		requested = min(cfg.perLetter, math.MaxInt/len(perLetterAlphabet)) * len(perLetterAlphabet)
+	}
+	if cfg.safetyLimit > 0 && requested > cfg.safetyLimit && !cfg.force {
+		diag.errorf("refusing to generate %d codes, more than the safety limit of %d (use -force to generate them anyway)", requested, cfg.safetyLimit)
+		os.Exit(1)
+	}
+	if cfg.output != "" && !cfg.force {
+		if _, err := os.Stat(cfg.output); err == nil {
+			diag.errorf("output file %q already exists (use -force to overwrite)", cfg.output)
 			os.Exit(1)
 		}
-		count = parsed
 	}
 
 	// Read words from dictionary
-	words, err := readWords()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if cfg.blocklist != "" {
+		cfg.filter.Blocked, err = readBlocklist(cfg.blocklist)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	var filterStats codegen.FilterStats
+	var genStats codegen.Stats
+	cfg.filter.Stats = &filterStats
+	if diag.enabled(levelInfo) || cfg.showStats {
+		cfg.opts.Stats = &genStats
+	}
+	if cfg.frequencies != "" {
+		cfg.opts.Weights, err = readFrequencies(cfg.frequencies)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+	var words []string
+	switch {
+	case cfg.opts.Numeric > 0:
+		// Numeric codes need no words
+	case cfg.pronounceable:
+		words = codegen.PseudoWords(pseudoWordCount, cfg.filter, cfg.opts.Seed)
+		diag.infof("Invented %d pronounceable words", len(words))
+	default:
+		source := "the embedded wordlist"
+		if len(cfg.dicts) == 0 {
+			if path := findDict(); path != "" {
+				cfg.dicts = []string{path}
+			} else {
+				diag.warnf("no system dictionary found, using the embedded wordlist")
+			}
+		}
+		if len(cfg.dicts) > 0 {
+			source = strings.Join(cfg.dicts, ", ")
+		}
+		words, err = readWords(cfg.dicts, cfg.filter)
+		if err != nil {
+			diag.errorf("%v", explainFiltered(err, cfg, filterStats.Read, filterStats.Accepted))
+			os.Exit(1)
+		}
+		diag.infof("Read %d words from %s: %d passed the filters, %d duplicates removed, %d unique",
+			filterStats.Read, source, filterStats.Accepted, filterStats.Accepted-len(words), len(words))
+	}
+	if _, err := codegen.MaxCombinations(words, cfg.opts); errors.Is(err, codegen.ErrInsufficientWords) {
+		diag.errorf("%v", explainFiltered(err, cfg, filterStats.Read, len(words)))
 		os.Exit(1)
 	}
+	if cfg.entropy {
+		bits, err := codegen.Entropy(words, cfg.opts)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Entropy: %.1f bits per code\n", bits)
+	}
 
 	// Generate promo codes
-	codes, err := generatePromoCodes(words, count)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if cfg.history != "" {
+		cfg.opts.Used, err = readCodes(cfg.history)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		diag.debugf("loaded %d used codes from history file %s", len(cfg.opts.Used), cfg.history)
+	}
+	if len(cfg.uniqueAcross) > 0 && cfg.opts.Used == nil {
+		cfg.opts.Used = make(map[string]bool)
+	}
+	for _, path := range cfg.uniqueAcross {
+		if err := addCodes(cfg.opts.Used, path); err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		diag.debugf("loaded codes from %s, %d used codes in total", path, len(cfg.opts.Used))
+	}
+
+	if cfg.dryRun {
+		feasible, err := dryRun(os.Stdout, cfg, words)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		if !feasible {
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Create and run the TUI
-	m := initialModel(codes)
-	p := tea.NewProgram(m)
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
+	// Stop generating on Ctrl+C, keeping the codes produced so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if cfg.bench {
+		err := benchmark(ctx, os.Stderr, cfg, words)
+		printStats(cfg, genStats)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		if ctx.Err() != nil {
+			os.Exit(exitInterrupted)
+		}
+		return
+	}
+
+	// Pick a non-interactive output format; the TUI is used when none applies
+	var format codeFormat
+	ext := "txt"
+	extras := codeExtras{urlTemplate: cfg.urlTemplate}
+	if cfg.expires > 0 {
+		extras.expires = time.Now().Add(cfg.expires).UTC().Format(time.RFC3339)
+	}
+	switch {
+	case cfg.jsonOutput:
+		format, ext = jsonFormat(extras), "json"
+	case cfg.csvOutput:
+		format, ext = csvFormat(extras), "csv"
+	case cfg.plainOutput || cfg.output != "" || cfg.batches > 0 || cfg.perLetter > 0 || cfg.phonetic || !isatty.IsTerminal(os.Stdout.Fd()):
+		format = newPlainWriter
+		if cfg.qr {
+			format = qrFormat(lipgloss.HasDarkBackground())
+		}
+		if cfg.phonetic {
+			format = newPhoneticWriter
+		}
+		if cfg.urlTemplate != "" {
+			format = urlFormat(cfg.urlTemplate, format)
+		}
+	}
+
+	if cfg.one {
+		codes, err := generateCodes(ctx, cfg, words)
+		printStats(cfg, genStats)
+		exitIfInterrupted(err, len(codes))
+		if err == nil && cfg.history != "" {
+			err = appendCodes(cfg.history, codes)
+		}
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		code := codes[0]
+		if cfg.urlTemplate != "" {
+			code = redeemURL(cfg.urlTemplate, code)
+		}
+		fmt.Println(code)
+		if err := clipboard.WriteAll(code); err != nil {
+			diag.warnf("could not copy the code to the clipboard: %v", err)
+		} else {
+			fmt.Fprintln(os.Stderr, "Copied the code to the clipboard")
+		}
+		return
+	}
+
+	if cfg.batches > 0 {
+		for i := 1; i <= cfg.batches && !cfg.force; i++ {
+			if _, err := os.Stat(batchPath(i, ext)); err == nil {
+				diag.errorf("output file %q already exists (use -force to overwrite)", batchPath(i, ext))
+				os.Exit(1)
+			}
+		}
+		bw := newBatchWriter(format, ext, batchSizes(cfg.count, cfg.batches), cfg.force)
+		n, err := writeGenerated(ctx, cfg, words, bw)
+		printStats(cfg, genStats)
+		for i, path := range bw.paths {
+			fmt.Fprintf(os.Stderr, "Wrote %d codes to %s\n", bw.counts[i], path)
+		}
+		exitIfInterrupted(err, n)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d codes in %d batches\n", n, len(bw.paths))
+		return
+	}
+
+	if cfg.perLetter > 0 {
+		n, err := writePerLetter(ctx, cfg, words, format)
+		printStats(cfg, genStats)
+		exitIfInterrupted(err, n)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		if cfg.output != "" {
+			fmt.Fprintf(os.Stderr, "Wrote %d codes to %s\n", n, cfg.output)
+		}
+		return
+	}
+
+	if format != nil {
+		n, err := streamCodes(ctx, cfg, words, format)
+		printStats(cfg, genStats)
+		exitIfInterrupted(err, n)
+		if err != nil {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+		if cfg.output != "" {
+			fmt.Fprintf(os.Stderr, "Wrote %d codes to %s\n", n, cfg.output)
+		}
+		return
+	}
+
+	tc := tuiConfig{
+		opts:        cfg.opts,
+		history:     cfg.history,
+		force:       cfg.force,
+		noColor:     noColor,
+		sorted:      cfg.sorted,
+		shuffled:    cfg.shuffled,
+		qr:          cfg.qr,
+		columns:     cfg.columns,
+		safetyLimit: cfg.safetyLimit,
+		check:       func(code string) error { return checkCode(cfg, code) },
+
+		colorMode:      cfg.colorMode,
+		colorSeed:      cfg.colorSeed,
+		palette:        palettes[cfg.palette],
+		minBrightness:  cfg.minBrightness,
+		darkBackground: lipgloss.HasDarkBackground(),
+		profile:        profile,
+	}
+
+	// Without a count, start at the options form if someone is at the
+	// terminal to fill it in
+	prompt := !cfg.countGiven && isatty.IsTerminal(os.Stdin.Fd())
+	var m model
+	if prompt {
+		m = formModel(words, tc)
+	} else {
+		codes, err := generateCodes(ctx, cfg, words)
+		printStats(cfg, genStats)
+		tc.opts.Stats = nil // regenerating in the TUI is not reported
+		if err != nil && !errors.Is(err, context.Canceled) {
+			diag.errorf("%v", err)
+			os.Exit(1)
+		}
+
+		if cfg.history != "" {
+			if err := appendCodes(cfg.history, codes); err != nil {
+				diag.errorf("%v", err)
+				os.Exit(1)
+			}
+		}
+		if errors.Is(err, context.Canceled) {
+			// Print the codes so far instead of starting the TUI
+			if err := writeCodes(newPlainWriter(os.Stdout), codes); err != nil {
+				diag.errorf("%v", err)
+			}
+			exitIfInterrupted(context.Canceled, len(codes))
+		}
+		m = initialModel(codes, words, tc)
+	}
+
+	// Run the TUI, which reads Ctrl+C as a key instead
+	stop()
+	var programOpts []tea.ProgramOption
+	if slices.Contains(cfg.dicts, "-") {
+		// stdin held the word list, so read keys from the terminal instead
+		programOpts = append(programOpts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(m, programOpts...)
+	final, err := p.Run()
+	if err != nil {
+		diag.errorf("running program: %v", err)
 		os.Exit(1)
 	}
+	if prompt && final.(model).codes != nil {
+		// The codes were generated from the form, so report them now that
+		// the screen is free
+		printStats(cfg, genStats)
+	}
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"promocodes/codegen"
+)
+
+func TestReadWordsMergesDictionaries(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	good := write("good.txt", "apple\ntree\nlamp\napple\n")
+	filtered := write("filtered.txt", "Paris\nx\n")
+	empty := write("empty.txt", "\n")
+
+	var stats codegen.FilterStats
+	filter := codegen.Filter{MinLen: 3, MaxLen: 6, Stats: &stats}
+	words, err := readWords([]string{good, filtered, empty}, filter)
+	if err != nil {
+		t.Fatalf("readWords: %v", err)
+	}
+	if want := []string{"apple", "tree", "lamp"}; !slices.Equal(words, want) {
+		t.Errorf("readWords = %v, want %v", words, want)
+	}
+	if stats.Read != 6 || stats.Accepted != 4 {
+		t.Errorf("stats = %+v, want 6 read and 4 accepted across the dictionaries", stats)
+	}
+
+	if _, err := readWords([]string{filtered, empty}, filter); !errors.Is(err, codegen.ErrInsufficientWords) {
+		t.Errorf("readWords without any accepted words: error = %v, want %v", err, codegen.ErrInsufficientWords)
+	}
+}
+
+func TestUniqueWordsIgnoresCase(t *testing.T) {
+	got := uniqueWords([]string{"Apple", "tree", "apple", "TREE"})
+	if want := []string{"Apple", "tree"}; !slices.Equal(got, want) {
+		t.Errorf("uniqueWords = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// codeWriter writes codes one at a time in some output format
+type codeWriter interface {
+	// WriteCode writes the next code
+	WriteCode(code string) error
+	// Close finishes the output, such as closing a JSON array. It does not
+	// close the underlying writer.
+	Close() error
+}
+
+// codeFormat creates a codeWriter writing to w
+type codeFormat func(w io.Writer) codeWriter
+
+// plainWriter writes codes one per line, without any styling
+type plainWriter struct{ w io.Writer }
+
+// newPlainWriter returns a codeWriter writing plain lines to w
+func newPlainWriter(w io.Writer) codeWriter { return &plainWriter{w: w} }
+
+// WriteCode writes code on its own line
+func (pw *plainWriter) WriteCode(code string) error {
+	if _, err := fmt.Fprintln(pw.w, code); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing, as plain output needs no trailer
+func (pw *plainWriter) Close() error { return nil }
+
+// qrWriter writes codes one per line, like plainWriter, each followed by its
+// QR code
+type qrWriter struct {
+	w              io.Writer
+	darkBackground bool // whether the QR codes are drawn for a dark background
+}
+
+// qrFormat returns a codeFormat writing codes and QR codes drawn for a dark
+// or light terminal background
+func qrFormat(darkBackground bool) codeFormat {
+	return func(w io.Writer) codeWriter { return &qrWriter{w, darkBackground} }
+}
+
+// WriteCode writes code on its own line, then its QR code
+func (qw *qrWriter) WriteCode(code string) error {
+	qr, err := qrText(code, qw.darkBackground)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(qw.w, "%s\n%s\n", code, qr); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing, as QR output needs no trailer
+func (qw *qrWriter) Close() error { return nil }
+
+// phoneticWriter writes codes one per line, like plainWriter, each followed
+// by its phonetic spelling
+type phoneticWriter struct{ w io.Writer }
+
+// newPhoneticWriter returns a codeWriter writing spelled out codes to w
+func newPhoneticWriter(w io.Writer) codeWriter { return &phoneticWriter{w: w} }
+
+// WriteCode writes code and its spelling on one line, such as
+// "apple -> Alpha Papa Papa Lima Echo"
+func (pw *phoneticWriter) WriteCode(code string) error {
+	if _, err := fmt.Fprintf(pw.w, "%s -> %s\n", code, phonetic(code)); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing, as phonetic output needs no trailer
+func (pw *phoneticWriter) Close() error { return nil }
+
+// codeExtras holds what structured output pairs each code with
+type codeExtras struct {
+	expires     string // RFC 3339 expiry time of every code, if not empty
+	urlTemplate string // redemption URL with {code} in place of the code, if not empty
+}
+
+// redeemURL returns template with every {code} replaced by code, escaped as
+// a path segment before the first ? and as a query value after it, where a
+// space becomes + instead of %20
+func redeemURL(template, code string) string {
+	path, query, hasQuery := strings.Cut(template, "?")
+	path = strings.ReplaceAll(path, "{code}", url.PathEscape(code))
+	if !hasQuery {
+		return path
+	}
+	return path + "?" + strings.ReplaceAll(query, "{code}", url.QueryEscape(code))
+}
+
+// urlWriter passes the redemption URLs of codes, instead of the codes, to
+// another codeWriter
+type urlWriter struct {
+	codeWriter
+	template string // URL with {code} in place of the code
+}
+
+// urlFormat returns a codeFormat writing the redemption URLs of codes, made
+// from template, in format
+func urlFormat(template string, format codeFormat) codeFormat {
+	return func(w io.Writer) codeWriter { return urlWriter{format(w), template} }
+}
+
+// WriteCode writes the URL for code
+func (uw urlWriter) WriteCode(code string) error {
+	return uw.codeWriter.WriteCode(redeemURL(uw.template, code))
+}
+
+// jsonWriter writes codes as a JSON array: of strings, the redemption URLs if
+// there is a URL template, or, with an expiry time, of objects with code,
+// url and expires fields
+type jsonWriter struct {
+	w      io.Writer
+	extras codeExtras
+	n      int // codes written so far
+}
+
+// jsonCode is a code with its extras in JSON output
+type jsonCode struct {
+	Code    string `json:"code"`
+	URL     string `json:"url,omitempty"`
+	Expires string `json:"expires"`
+}
+
+// jsonFormat returns a codeFormat writing a JSON array, with the codes
+// paired with extras
+func jsonFormat(extras codeExtras) codeFormat {
+	return func(w io.Writer) codeWriter { return &jsonWriter{w: w, extras: extras} }
+}
+
+// WriteCode writes code as the next array element
+func (jw *jsonWriter) WriteCode(code string) error {
+	var v any = code
+	switch x := jw.extras; {
+	case x.expires != "":
+		v = jsonCode{Code: code, Expires: x.expires}
+		if x.urlTemplate != "" {
+			v = jsonCode{code, redeemURL(x.urlTemplate, code), x.expires}
+		}
+	case x.urlTemplate != "":
+		v = redeemURL(x.urlTemplate, code)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to write JSON output: %w", err)
+	}
+	sep := ","
+	if jw.n == 0 {
+		sep = "["
+	}
+	jw.n++
+	if _, err := io.WriteString(jw.w, sep+string(b)); err != nil {
+		return fmt.Errorf("failed to write JSON output: %w", err)
+	}
+	return nil
+}
+
+// Close ends the array
+func (jw *jsonWriter) Close() error {
+	end := "]\n"
+	if jw.n == 0 {
+		end = "[]\n"
+	}
+	if _, err := io.WriteString(jw.w, end); err != nil {
+		return fmt.Errorf("failed to write JSON output: %w", err)
+	}
+	return nil
+}
+
+// csvWriter writes codes as CSV rows of index and code, after an
+// "index,code" header, with url and expires columns if there is a URL
+// template and an expiry time
+type csvWriter struct {
+	w      *csv.Writer
+	extras codeExtras
+	n      int // codes written so far
+}
+
+// csvFormat returns a codeFormat writing CSV rows, with columns for extras
+func csvFormat(extras codeExtras) codeFormat {
+	return func(w io.Writer) codeWriter { return &csvWriter{w: csv.NewWriter(w), extras: extras} }
+}
+
+// WriteCode writes code as the next row, preceded by the header for the first
+func (cw *csvWriter) WriteCode(code string) error {
+	if cw.n == 0 {
+		if err := cw.writeHeader(); err != nil {
+			return err
+		}
+	}
+	cw.n++
+	row := []string{strconv.Itoa(cw.n), code}
+	if cw.extras.urlTemplate != "" {
+		row = append(row, redeemURL(cw.extras.urlTemplate, code))
+	}
+	if cw.extras.expires != "" {
+		row = append(row, cw.extras.expires)
+	}
+	if err := cw.w.Write(row); err != nil {
+		return fmt.Errorf("failed to write CSV output: %w", err)
+	}
+	return nil
+}
+
+// writeHeader writes the row naming the columns
+func (cw *csvWriter) writeHeader() error {
+	header := []string{"index", "code"}
+	if cw.extras.urlTemplate != "" {
+		header = append(header, "url")
+	}
+	if cw.extras.expires != "" {
+		header = append(header, "expires")
+	}
+	if err := cw.w.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV output: %w", err)
+	}
+	return nil
+}
+
+// Close writes the header if no rows were written and flushes the rows
+func (cw *csvWriter) Close() error {
+	if cw.n == 0 {
+		if err := cw.writeHeader(); err != nil {
+			return err
+		}
+	}
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV output: %w", err)
+	}
+	return nil
+}
+
+// writeCodes writes all codes with cw and finishes the output
+func writeCodes(cw codeWriter, codes []string) error {
+	for _, code := range codes {
+		if err := cw.WriteCode(code); err != nil {
+			return err
+		}
+	}
+	return cw.Close()
+}
+
+// bufferedFile is a file written through a buffer
+type bufferedFile struct {
+	*bufio.Writer
+	file *os.File
+}
+
+// Close flushes the buffer and closes the file
+func (f bufferedFile) Close() error {
+	return errors.Join(f.Flush(), f.file.Close())
+}
+
+// batchPath returns the name of the file holding batch i, counting from 1
+func batchPath(i int, ext string) string {
+	return fmt.Sprintf("batch-%d.%s", i, ext)
+}
+
+// batchWriter is a codeWriter spreading codes over the numbered files
+// batch-1.<ext>, batch-2.<ext> and so on, each written in its own format
+type batchWriter struct {
+	format codeFormat
+	ext    string
+	force  bool
+	sizes  []int    // number of codes in each batch
+	paths  []string // files created so far
+	counts []int    // codes written to each file created so far
+
+	file bufferedFile // file of the current batch
+	out  codeWriter   // writer of the current batch, nil before the first code
+}
+
+// newBatchWriter returns a batchWriter writing len(sizes) batches of the given
+// sizes in format, overwriting existing files only if force is set
+func newBatchWriter(format codeFormat, ext string, sizes []int, force bool) *batchWriter {
+	return &batchWriter{format: format, ext: ext, sizes: sizes, force: force}
+}
+
+// batchSizes splits count into n near-equal sizes, the first ones taking the
+// remainder
+func batchSizes(count, n int) []int {
+	sizes := make([]int, n)
+	for i := range sizes {
+		sizes[i] = count / n
+		if i < count%n {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
+
+// WriteCode writes code to the current batch, starting the next one when it
+// is full
+func (bw *batchWriter) WriteCode(code string) error {
+	if bw.out == nil || bw.counts[len(bw.counts)-1] == bw.sizes[len(bw.counts)-1] {
+		if err := bw.Close(); err != nil {
+			return err
+		}
+		if len(bw.paths) == len(bw.sizes) {
+			return fmt.Errorf("more codes than the %d batches hold", len(bw.sizes))
+		}
+		path := batchPath(len(bw.paths)+1, bw.ext)
+		file, err := createOutput(path, bw.force)
+		if err != nil {
+			return err
+		}
+		bw.paths, bw.counts = append(bw.paths, path), append(bw.counts, 0)
+		bw.file, bw.out = file, bw.format(file)
+	}
+	bw.counts[len(bw.counts)-1]++
+	return bw.out.WriteCode(code)
+}
+
+// Close finishes and closes the current batch file, if any
+func (bw *batchWriter) Close() error {
+	if bw.out == nil {
+		return nil
+	}
+	err := errors.Join(bw.out.Close(), bw.file.Close())
+	bw.out = nil
+	return err
+}
+
+// createOutput creates the -output file at path. An existing file is only
+// replaced when force is set.
+func createOutput(path string, force bool) (bufferedFile, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return bufferedFile{}, fmt.Errorf("output file %q already exists (use -force to overwrite)", path)
+	}
+	if err != nil {
+		return bufferedFile{}, fmt.Errorf("failed to create output file %q: %w", path, err)
+	}
+	return bufferedFile{bufio.NewWriter(file), file}, nil
+}
+
+// openHistory opens the history file at path for appending, creating it if
+// needed
+func openHistory(path string) (bufferedFile, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return bufferedFile{}, fmt.Errorf("failed to open history file %q: %w", path, err)
+	}
+	return bufferedFile{bufio.NewWriter(file), file}, nil
+}
+
+// readCodes reads previously generated codes from path, one per line. A
+// missing file is treated as empty.
+func readCodes(path string) (map[string]bool, error) {
+	codes := make(map[string]bool)
+	if err := addCodes(codes, path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// addCodes adds the codes listed in the file at path, one per line, to codes,
+// ignoring surrounding whitespace and blank lines
+func addCodes(codes map[string]bool, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open code file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if code := strings.TrimSpace(scanner.Text()); code != "" {
+			codes[code] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading code file %q: %w", path, err)
+	}
+	return nil
+}
+
+// appendCodes appends codes to the history file at path, one per line
+func appendCodes(path string, codes []string) error {
+	history, err := openHistory(path)
+	if err != nil {
+		return err
+	}
+	return errors.Join(writeCodes(newPlainWriter(history), codes), history.Close())
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// phoneticWords are the NATO phonetic alphabet and the spoken digits, keyed
+// by lowercase character
+var phoneticWords = map[rune]string{
+	'a': "Alpha", 'b': "Bravo", 'c': "Charlie", 'd': "Delta", 'e': "Echo",
+	'f': "Foxtrot", 'g': "Golf", 'h': "Hotel", 'i': "India", 'j': "Juliett",
+	'k': "Kilo", 'l': "Lima", 'm': "Mike", 'n': "November", 'o': "Oscar",
+	'p': "Papa", 'q': "Quebec", 'r': "Romeo", 's': "Sierra", 't': "Tango",
+	'u': "Uniform", 'v': "Victor", 'w': "Whiskey", 'x': "X-ray", 'y': "Yankee",
+	'z': "Zulu",
+	'0': "Zero", '1': "One", '2': "Two", '3': "Three", '4': "Four",
+	'5': "Five", '6': "Six", '7': "Seven", '8': "Eight", '9': "Nine",
+	'-': "Dash", '_': "Underscore", '.': "Dot", ' ': "Space",
+}
+
+// phonetic spells code out for reading aloud, one word per character, such
+// as "Alpha Papa Papa Lima Echo" for apple. Characters without a phonetic
+// word are kept as they are.
+func phonetic(code string) string {
+	var words []string
+	for _, r := range code {
+		if word, ok := phoneticWords[unicode.ToLower(r)]; ok {
+			words = append(words, word)
+		} else {
+			words = append(words, string(r))
+		}
+	}
+	return strings.Join(words, " ")
+}
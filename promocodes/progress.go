@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	progressThreshold = 1000                   // only report progress for larger batches
+	progressInterval  = 100 * time.Millisecond // minimum time between updates
+)
+
+// progress reports how many codes have been generated so far on a single,
+// repeatedly rewritten terminal line
+type progress struct {
+	w     io.Writer // nil disables reporting
+	total int
+	last  time.Time
+}
+
+// newProgress returns a progress for a batch of total codes, writing to w if
+// the batch is large enough to be worth reporting on
+func newProgress(w io.Writer, total int) *progress {
+	if total <= progressThreshold {
+		w = nil
+	}
+	return &progress{w: w, total: total}
+}
+
+// update reports that done codes have been generated
+func (p *progress) update(done int) {
+	if p.w == nil || done%100 != 0 {
+		return
+	}
+	if now := time.Now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		fmt.Fprintf(p.w, "\rGenerated %d/%d codes", done, p.total)
+	}
+}
+
+// done clears the progress line
+func (p *progress) done() {
+	if p.w != nil && !p.last.IsZero() {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Room the QR code of -qr needs in the TUI
+const (
+	qrGap          = 2  // columns between the codes and the QR code
+	qrMinCodeWidth = 12 // columns left for the codes
+)
+
+// qrText renders text as a QR code of Unicode half blocks, two modules to a
+// character cell, drawing the light modules in the foreground color on dark
+// backgrounds and the dark ones on light backgrounds
+func qrText(text string, darkBackground bool) (string, error) {
+	q, err := qrcode.New(text, qrcode.Medium)
+	if err != nil {
+		return "", fmt.Errorf("failed to make QR code: %w", err)
+	}
+	return strings.TrimSuffix(q.ToSmallString(!darkBackground), "\n"), nil
+}
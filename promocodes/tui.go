@@ -0,0 +1,798 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+
+	"promocodes/codegen"
+)
+
+// tuiConfig holds the command-line settings the TUI needs
+type tuiConfig struct {
+	opts     codegen.Options // options used to regenerate codes
+	history  string          // file regenerated codes are recorded in, if any
+	output   string          // file codes were last saved to, or "" for a timestamped name
+	force    bool            // overwrite the output file if it already exists
+	noColor  bool            // render codes in the terminal's default foreground
+	sorted   bool            // show each batch of codes in alphabetical order
+	shuffled bool            // show each batch of codes in random order
+	qr       bool            // show the QR code of the highlighted code
+	columns  int             // columns to lay the codes out in, 0 to fit them to the terminal
+
+	// check returns an error for a code the command line would refuse, see
+	// checkCode; nil accepts every code
+	check func(code string) error
+
+	// safetyLimit is the largest count accepted at the count prompt unless
+	// force is set, 0 for no limit
+	safetyLimit int
+
+	colorMode      string           // colorModeRandom or colorModeHash
+	colorSeed      int64            // seed of the random colors
+	palette        []lipgloss.Color // colors to choose from; random RGB if empty
+	minBrightness  float64          // brightness floor for code colors, see randomColor
+	darkBackground bool             // whether the terminal background is dark
+	profile        termenv.Profile  // colors the terminal can show
+}
+
+// state is the screen the TUI is showing
+type state int
+
+const (
+	stateResults state = iota // the generated codes
+	stateForm                 // the options form, see form.go
+)
+
+// model represents the application state
+type model struct {
+	tuiConfig
+	state  state             // the screen being shown
+	inputs []textinput.Model // fields of the options form, indexed by field
+	focus  int               // field of the options form being edited
+	codes  []string
+	colors []lipgloss.Color // color of each code, assigned once per batch
+	rng    *rand.Rand       // source of code colors
+	words  []string         // dictionary the codes are drawn from
+	count  int              // number of codes per batch
+	cursor int              // position of the highlighted code among the visible ones
+	status string           // short confirmation shown below the codes
+	err    error            // last error, shown below the codes
+
+	filter    string // only codes containing this, case-insensitively, are shown
+	filtering bool   // whether typed keys edit the filter
+
+	width  int // terminal width, 0 until the first tea.WindowSizeMsg
+	height int // terminal height, 0 until the first tea.WindowSizeMsg
+	offset int // position of the first visible code on screen
+}
+
+// initialModel returns the initial model
+func initialModel(codes, words []string, cfg tuiConfig) model {
+	m := model{
+		tuiConfig: cfg,
+		rng:       rand.New(rand.NewSource(cfg.colorSeed)),
+		words:     words,
+		count:     len(codes),
+	}
+	m.setCodes(codes)
+	return m
+}
+
+// setCodes replaces the displayed codes, sorting or shuffling them if set to,
+// and assigns each a new color
+func (m *model) setCodes(codes []string) {
+	if m.sorted {
+		slices.Sort(codes)
+	} else if m.shuffled {
+		codegen.Shuffle(codes, m.opts)
+	}
+	m.codes = codes
+	m.colors = make([]lipgloss.Color, len(codes))
+	for i := range codes {
+		m.colors[i] = m.colorFor(codes[i])
+	}
+}
+
+// colorFor picks the color for code, from the palette if one is set. In
+// colorModeHash the color depends only on the code, otherwise it is random.
+func (m *model) colorFor(code string) lipgloss.Color {
+	if m.colorMode == colorModeHash {
+		h := fnv.New32a()
+		h.Write([]byte(code))
+		sum := h.Sum32()
+		if len(m.palette) > 0 {
+			return m.palette[sum%uint32(len(m.palette))]
+		}
+		return hashColor(sum, m.minBrightness, m.darkBackground, m.profile)
+	}
+	if len(m.palette) > 0 {
+		return m.palette[m.rng.Intn(len(m.palette))]
+	}
+	return randomColor(m.rng, m.minBrightness, m.darkBackground, m.profile)
+}
+
+// visible returns the indexes of the codes matching the filter
+func (m model) visible() []int {
+	filter := strings.ToLower(m.filter)
+	var indexes []int
+	for i, code := range m.codes {
+		if strings.Contains(strings.ToLower(code), filter) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// selected returns the index in m.codes of the highlighted code, or -1 if no
+// code is visible
+func (m model) selected() int {
+	visible := m.visible()
+	if m.cursor < len(visible) {
+		return visible[m.cursor]
+	}
+	return -1
+}
+
+// historyErrMsg reports a failure to record regenerated codes
+type historyErrMsg struct{ err error }
+
+// copiedMsg reports the outcome of copying n codes to the clipboard
+type copiedMsg struct {
+	n   int
+	err error
+}
+
+// savedMsg reports the outcome of saving n codes to path
+type savedMsg struct {
+	path string
+	n    int
+	err  error
+}
+
+// Init is called when the program starts
+func (m model) Init() tea.Cmd {
+	if m.state == stateForm {
+		return textinput.Blink
+	}
+	return nil
+}
+
+// Update handles messages
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := m.update(msg)
+	return m.scroll(), cmd
+}
+
+// update handles msg, leaving scrolling to Update
+func (m model) update(msg tea.Msg) (model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		m.status = ""
+		if m.state == stateForm {
+			return m.updateForm(msg)
+		}
+		if m.filtering {
+			return m.updateFilter(msg)
+		}
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "r":
+			return m.regenerate()
+		case "/":
+			m.filtering = true
+		case "esc":
+			if m.filter == "" {
+				return m.showForm()
+			}
+			m = m.clearFilter()
+		case "up", "k":
+			if _, cols, _ := m.grid(); m.cursor >= cols {
+				m.cursor -= cols
+			}
+		case "down", "j":
+			// Move to the last code from the row above a shorter last row
+			n := len(m.visible())
+			if _, cols, _ := m.grid(); m.cursor+cols < n {
+				m.cursor += cols
+			} else if m.cursor/cols < (n-1)/cols {
+				m.cursor = n - 1
+			}
+		case "left", "h":
+			if _, cols, _ := m.grid(); m.cursor%cols > 0 {
+				m.cursor--
+			}
+		case "right", "l":
+			if _, cols, _ := m.grid(); m.cursor%cols < cols-1 && m.cursor < len(m.visible())-1 {
+				m.cursor++
+			}
+		case "pgup":
+			m.cursor = max(m.cursor-m.pageSize(), 0)
+		case "pgdown":
+			m.cursor = max(min(m.cursor+m.pageSize(), len(m.visible())-1), 0)
+		case "home", "g":
+			m.cursor = 0
+		case "end", "G":
+			m.cursor = max(len(m.visible())-1, 0)
+		case "c", "enter":
+			if i := m.selected(); i >= 0 {
+				return m, copyCodes(m.codes[i : i+1])
+			}
+		case "y":
+			if len(m.codes) > 0 {
+				return m, copyCodes(m.codes)
+			}
+		case "d":
+			if i := m.selected(); i >= 0 {
+				m.codes = slices.Delete(m.codes, i, i+1)
+				m.colors = slices.Delete(m.colors, i, i+1)
+				m.cursor = max(min(m.cursor, len(m.visible())-1), 0)
+			}
+		case "x":
+			if i := m.selected(); i >= 0 {
+				return m.replace(i)
+			}
+		case "s":
+			return m, m.save()
+		}
+	case historyErrMsg:
+		m.err = msg.err
+	case savedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+		} else {
+			m.status, m.err = fmt.Sprintf("saved %d codes to %s", msg.n, msg.path), nil
+			// Saving again updates the same file
+			m.output, m.force = msg.path, true
+		}
+	case copiedMsg:
+		if msg.err != nil {
+			m.err = fmt.Errorf("failed to copy to clipboard: %w", msg.err)
+		} else {
+			m.status, m.err = "copied!", nil
+			if msg.n > 1 {
+				m.status = fmt.Sprintf("copied %d codes!", msg.n)
+			}
+		}
+	default:
+		if m.state == stateForm {
+			// Let the focused field blink its cursor
+			var cmd tea.Cmd
+			m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+// updateFilter handles keys typed while editing the filter: enter keeps the
+// filter and esc clears it, both returning to the normal keys
+func (m model) updateFilter(msg tea.KeyMsg) (model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m = m.clearFilter()
+	case tea.KeyBackspace:
+		if m.filter != "" {
+			_, size := utf8.DecodeLastRuneInString(m.filter)
+			m.filter = m.filter[:len(m.filter)-size]
+			m.cursor = 0
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.filter += string(msg.Runes)
+		m.cursor = 0
+	}
+	return m, nil
+}
+
+// clearFilter shows all codes again, keeping the highlighted code selected
+func (m model) clearFilter() model {
+	if i := m.selected(); i >= 0 {
+		m.cursor = i
+	} else {
+		m.cursor = 0
+	}
+	m.filter, m.filtering = "", false
+	return m
+}
+
+// pageSize returns how many codes fit on screen above the footer, or the
+// number of visible codes while the terminal height is unknown
+func (m model) pageSize() int {
+	_, cols, rows := m.grid()
+	return cols * rows
+}
+
+// columnGap is the number of spaces between columns of codes
+const columnGap = 2
+
+// grid returns the width left for the codes beside the QR code of -qr, and
+// the number of columns and rows of codes that fit on screen
+func (m model) grid() (width, cols, rows int) {
+	width = m.width
+	if qr, ok := m.selectedQR(); ok && width > 0 {
+		width -= lipgloss.Width(qr) + qrGap
+	}
+	footer := m.footer()
+	cols = m.columnsFor(width, footer)
+	return width, cols, m.rowsAbove(footer, cols)
+}
+
+// columnsFor returns how many columns to lay the visible codes out in within
+// width, above footer: the -columns setting, or else the fewest that fit all
+// of them on screen, at most as many as fit side by side. Without a known
+// terminal size the codes go in a single column unless -columns is set.
+func (m model) columnsFor(width int, footer string) int {
+	if width <= 0 || m.height <= 0 {
+		return max(m.columns, 1)
+	}
+	visible := m.visible()
+	widest := 1
+	for _, i := range visible {
+		widest = max(widest, lipgloss.Width(m.codes[i]))
+	}
+	fits := max((width+columnGap)/(widest+columnGap), 1)
+	if m.columns > 0 {
+		return min(m.columns, fits)
+	}
+	rows := max(m.height-strings.Count(footer, "\n"), 1)
+	return max(min((len(visible)+rows-1)/rows, fits), 1)
+}
+
+// rowsAbove returns how many rows of codes in cols columns fit on screen
+// above footer, or the number of rows of visible codes while the terminal
+// height is unknown
+func (m model) rowsAbove(footer string, cols int) int {
+	n := len(m.visible())
+	needed := (n + cols - 1) / cols
+	if m.height <= 0 {
+		return needed
+	}
+	rows := m.height - strings.Count(footer, "\n")
+	if needed > rows {
+		// Make room for the scroll position, at its widest
+		rows -= 1 + lipgloss.Height(m.wrap(scrollInfo(n, n, n)))
+	}
+	return max(rows, 1)
+}
+
+// scroll moves the offset, always to the start of a row, so that the
+// highlighted code is on screen and no rows are left empty below the last
+// code
+func (m model) scroll() model {
+	_, cols, rows := m.grid()
+	row, first := m.cursor/cols, m.offset/cols
+	if row < first {
+		first = row
+	}
+	if row >= first+rows {
+		first = row - rows + 1
+	}
+	total := (len(m.visible()) + cols - 1) / cols
+	m.offset = max(min(first, total-rows), 0) * cols
+	return m
+}
+
+// copyCodes returns a command copying codes, one per line, to the system
+// clipboard
+func copyCodes(codes []string) tea.Cmd {
+	text := strings.Join(codes, "\n")
+	return func() tea.Msg {
+		return copiedMsg{len(codes), clipboard.WriteAll(text)}
+	}
+}
+
+// save returns a command writing the codes, one per line, to the file they
+// were last saved to, or to ghouls-<timestamp>.txt if they were not saved yet
+func (m model) save() tea.Cmd {
+	path := m.output
+	if path == "" {
+		path = "ghouls-" + time.Now().Format("20060102-150405") + ".txt"
+	}
+	codes := slices.Clone(m.codes)
+	return func() tea.Msg {
+		file, err := createOutput(path, m.force)
+		if err != nil {
+			return savedMsg{path, 0, err}
+		}
+		err = errors.Join(writeCodes(newPlainWriter(file), codes), file.Close())
+		return savedMsg{path, len(codes), err}
+	}
+}
+
+// regenerate replaces the codes with a fresh batch of the same size. A fixed
+// seed is advanced so that regenerated batches stay reproducible.
+func (m model) regenerate() (model, tea.Cmd) {
+	m.opts.Seed++
+	if m.history != "" {
+		for _, code := range m.codes {
+			m.opts.Used[code] = true
+		}
+	}
+
+	codes, err := m.generate(m.count, m.opts)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	m.setCodes(codes)
+	m.cursor, m.err = 0, nil
+	return m, m.record(codes)
+}
+
+// generate generates count codes from the dictionary with opts, failing if
+// any of them does not pass m.check
+func (m model) generate(count int, opts codegen.Options) ([]string, error) {
+	codes, err := codegen.Generate(m.words, count, opts)
+	if err != nil {
+		return nil, err
+	}
+	if m.check != nil {
+		for _, code := range codes {
+			if err := m.check(code); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return codes, nil
+}
+
+// replace swaps the code at index i for a newly generated one that differs
+// from every code in the list, including the one it replaces. Guarantees
+// about the batch as a whole cannot be kept for a single code, so it is
+// refused under them.
+func (m model) replace(i int) (model, tea.Cmd) {
+	if m.opts.UniqueWords || m.opts.MinUniqueWords > 0 || m.opts.MinDistance > 1 {
+		m.status = "x is unavailable with -unique-words, -min-unique-words or -min-distance; press r to regenerate the whole batch"
+		return m, nil
+	}
+	m.opts.Seed++
+	opts := m.opts
+	opts.Used = maps.Clone(m.opts.Used)
+	if opts.Used == nil {
+		opts.Used = make(map[string]bool)
+	}
+	for _, code := range m.codes {
+		opts.Used[code] = true
+	}
+
+	codes, err := m.generate(1, opts)
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	m.codes[i], m.colors[i] = codes[0], m.colorFor(codes[0])
+	m.err = nil
+	return m, m.record(codes)
+}
+
+// record returns a command appending codes to the history file, or nil if
+// there is none
+func (m model) record(codes []string) tea.Cmd {
+	if m.history == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := appendCodes(m.history, codes); err != nil {
+			return historyErrMsg{err}
+		}
+		return nil
+	}
+}
+
+// View renders the UI
+func (m model) View() string {
+	if m.state == stateForm {
+		return m.formView()
+	}
+	qr, showQR := m.selectedQR()
+	width, cols, rows := m.grid()
+
+	// Columns are as wide as the widest code, which fits the screen unless
+	// -columns asks for more than do
+	visible := m.visible()
+	cellWidth := width
+	if cols > 1 {
+		widest := 0
+		for _, i := range visible {
+			widest = max(widest, lipgloss.Width(m.codes[i]))
+		}
+		cellWidth = widest
+		if width > 0 {
+			cellWidth = min(widest, (width-columnGap*(cols-1))/cols)
+		}
+	}
+
+	var sb strings.Builder
+	end := min(m.offset+cols*rows, len(visible))
+	for pos := m.offset; pos < end; pos++ {
+		i := visible[pos]
+		style := lipgloss.NewStyle()
+		if !m.noColor {
+			style = style.Foreground(m.colors[i])
+		}
+		// Highlight the selected code
+		if pos == m.cursor {
+			style = style.Reverse(true)
+		}
+		cell := style.Render(fit(m.codes[i], cellWidth))
+		switch {
+		case pos == end-1:
+			sb.WriteString(cell)
+		case (pos-m.offset)%cols == cols-1:
+			sb.WriteString(cell + "\n")
+		default:
+			sb.WriteString(cell + strings.Repeat(" ", max(cellWidth-lipgloss.Width(cell), 0)+columnGap))
+		}
+	}
+	switch {
+	case len(m.codes) == 0:
+		sb.WriteString("no codes left (r to regenerate)")
+	case len(visible) == 0:
+		sb.WriteString("no codes match")
+	}
+	body := sb.String()
+	if showQR {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, body, strings.Repeat(" ", qrGap), qr)
+	}
+	if end-m.offset < len(visible) {
+		body += "\n\n" + m.wrap(scrollInfo(m.offset+1, end, len(visible)))
+	}
+	return body + m.footer()
+}
+
+// selectedQR returns the QR code of the highlighted code with -qr, and
+// whether it fits on screen to the right of the codes
+func (m model) selectedQR() (string, bool) {
+	i := m.selected()
+	if !m.qr || m.state != stateResults || i < 0 {
+		return "", false
+	}
+	qr, err := qrText(m.codes[i], m.darkBackground)
+	if err != nil {
+		return "", false
+	}
+	if m.width > 0 && lipgloss.Width(qr)+qrGap+qrMinCodeWidth > m.width {
+		return qr, false
+	}
+	codeWidth := m.width - lipgloss.Width(qr) - qrGap
+	if m.height > 0 && lipgloss.Height(qr) > m.rowsAbove(m.messages(), m.columnsFor(codeWidth, m.messages())) {
+		return qr, false
+	}
+	return qr, true
+}
+
+// scrollInfo describes which of total codes are on screen
+func scrollInfo(first, last, total int) string {
+	return fmt.Sprintf("%d-%d of %d (pgup/pgdown to scroll)", first, last, total)
+}
+
+// footer renders the lines shown below the codes, each after a blank line:
+// the messages, and a hint if the QR code of -qr does not fit
+func (m model) footer() string {
+	footer := m.messages()
+	if qr, ok := m.selectedQR(); qr != "" && !ok {
+		footer += "\n\n" + m.wrap("enlarge the terminal to see the QR code")
+	}
+	return footer
+}
+
+// messages renders the filter, status and error lines, each after a blank
+// line
+func (m model) messages() string {
+	var sb strings.Builder
+	switch {
+	case m.filtering:
+		sb.WriteString("\n\n" + m.wrap("/"+m.filter))
+	case m.filter != "":
+		sb.WriteString("\n\n" + m.wrap("filter: "+m.filter+" (esc to clear)"))
+	}
+	if m.status != "" {
+		sb.WriteString("\n\n" + m.wrap(m.status))
+	}
+	if m.err != nil {
+		sb.WriteString("\n\n" + m.wrap("Error: "+m.err.Error()))
+	}
+	return sb.String()
+}
+
+// fit truncates code to width, once the terminal width is known, so that
+// every code takes up a single row
+func fit(code string, width int) string {
+	if width <= 0 {
+		return code
+	}
+	return ansi.Truncate(code, width, "…")
+}
+
+// wrap breaks text into lines no wider than the terminal, once its width is
+// known
+func (m model) wrap(text string) string {
+	if m.width <= 0 {
+		return text
+	}
+	return lipgloss.NewStyle().Width(m.width).Render(text)
+}
+
+// palettes are the named color themes selectable with -palette
+var palettes = map[string][]lipgloss.Color{
+	"pastel": {"#ffb3ba", "#ffdfba", "#ffffba", "#baffc9", "#bae1ff", "#d7baff", "#ffbaf2", "#c9f2e4"},
+	"neon":   {"#ff073a", "#ff6ec7", "#fe019a", "#bc13fe", "#0ff0fc", "#39ff14", "#ccff00", "#ffad00"},
+	"mono":   {"#5f5f5f", "#767676", "#8a8a8a", "#9e9e9e", "#b2b2b2", "#c6c6c6", "#dadada", "#eeeeee"},
+}
+
+// paletteNames returns the names of all palettes in sorted order
+func paletteNames() []string {
+	names := make([]string, 0, len(palettes))
+	for name := range palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Color modes selectable with -color-mode
+const (
+	colorModeRandom = "random" // a new random color per code each batch
+	colorModeHash   = "hash"   // a color derived from the code, the same across runs
+)
+
+// previewColors writes the colors codes get with cfg, the whole palette or a
+// sample of random ones, as a grid of swatches labeled with their hex values,
+// or ANSI color numbers on terminals without true color
+func previewColors(w io.Writer, cfg tuiConfig) error {
+	colors := cfg.palette
+	if len(colors) == 0 {
+		rng := rand.New(rand.NewSource(cfg.colorSeed))
+		for range previewSwatches {
+			if cfg.colorMode == colorModeHash {
+				colors = append(colors, hashColor(rng.Uint32(), cfg.minBrightness, cfg.darkBackground, cfg.profile))
+			} else {
+				colors = append(colors, randomColor(rng, cfg.minBrightness, cfg.darkBackground, cfg.profile))
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i, color := range colors {
+		if i%previewColumns > 0 {
+			b.WriteString("  ")
+		}
+		style := lipgloss.NewStyle()
+		if !cfg.noColor {
+			style = style.Foreground(color)
+		}
+		b.WriteString(style.Render("████ " + string(color)))
+		if (i+1)%previewColumns == 0 || i == len(colors)-1 {
+			b.WriteString("\n")
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// randomColor generates a random color that profile can show, whose
+// brightness measured against the terminal background is at least
+// minBrightness (0-1). On dark backgrounds this rejects colors that are too
+// dark; on light ones, colors that are too light.
+func randomColor(rng *rand.Rand, minBrightness float64, darkBackground bool, profile termenv.Profile) lipgloss.Color {
+	var color lipgloss.Color
+	for i := 0; i < maxColorRolls; i++ {
+		var brightness float64
+		color, brightness = inProfile(rng.Intn(256), rng.Intn(256), rng.Intn(256), profile)
+		if !darkBackground {
+			brightness = 1 - brightness
+		}
+		if brightness >= minBrightness {
+			break
+		}
+	}
+	return color
+}
+
+// hashColor returns the color for a code hashing to sum: a hue taken from
+// sum, lightened on dark backgrounds (or darkened on light ones) until its
+// brightness reaches minBrightness, as in randomColor
+func hashColor(sum uint32, minBrightness float64, darkBackground bool, profile termenv.Profile) lipgloss.Color {
+	hue := float64(sum % 360)
+	step := 0.05
+	if !darkBackground {
+		step = -step
+	}
+	var color lipgloss.Color
+	for lightness := 0.5; lightness >= 0 && lightness <= 1; lightness += step {
+		r, g, b := hslToRGB(hue, 0.7, lightness)
+		var brightness float64
+		color, brightness = inProfile(r, g, b, profile)
+		if !darkBackground {
+			brightness = 1 - brightness
+		}
+		if brightness >= minBrightness {
+			break
+		}
+	}
+	return color
+}
+
+// inProfile returns the color nearest to the sRGB color r, g, b that profile
+// can show, as a hex value for true color terminals or an ANSI color number
+// otherwise, and its luminance. Brightness floors are checked against the
+// color actually shown, since the nearest ANSI color can be much darker.
+func inProfile(r, g, b int, profile termenv.Profile) (lipgloss.Color, float64) {
+	hex := fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	var number int
+	switch c := profile.Color(hex).(type) {
+	case termenv.ANSIColor:
+		number = int(c)
+	case termenv.ANSI256Color:
+		number = int(c)
+	default:
+		return lipgloss.Color(hex), luminance(r, g, b)
+	}
+	rgb := termenv.ConvertToRGB(termenv.ANSI256Color(number))
+	scale := func(v float64) int { return int(math.Round(v * 255)) }
+	return lipgloss.Color(strconv.Itoa(number)), luminance(scale(rgb.R), scale(rgb.G), scale(rgb.B))
+}
+
+// hslToRGB converts a color given by hue (0-360), saturation and lightness
+// (0-1) to sRGB components (0-255)
+func hslToRGB(hue, saturation, lightness float64) (r, g, b int) {
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var rf, gf, bf float64
+	switch {
+	case hue < 60:
+		rf, gf = chroma, x
+	case hue < 120:
+		rf, gf = x, chroma
+	case hue < 180:
+		gf, bf = chroma, x
+	case hue < 240:
+		gf, bf = x, chroma
+	case hue < 300:
+		rf, bf = x, chroma
+	default:
+		rf, bf = chroma, x
+	}
+	m := lightness - chroma/2
+	scale := func(c float64) int { return int(math.Round((c + m) * 255)) }
+	return scale(rf), scale(gf), scale(bf)
+}
+
+// luminance returns the relative luminance (0-1) of an sRGB color, as
+// defined by WCAG 2
+func luminance(r, g, b int) float64 {
+	linear := func(c int) float64 {
+		v := float64(c) / 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"promocodes/codegen"
+)
+
+func TestReplaceRefusedUnderBatchOptions(t *testing.T) {
+	words := []string{"apple", "tree", "lamp", "door", "cloud", "river", "stone", "bread"}
+	for _, opts := range []codegen.Options{
+		{WordsPerCode: 2, Separator: "-", UniqueWords: true},
+		{WordsPerCode: 2, Separator: "-", MinUniqueWords: 5},
+		{WordsPerCode: 2, Separator: "-", MinDistance: 3},
+	} {
+		codes, err := codegen.Generate(words, 3, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := initialModel(slices.Clone(codes), words, tuiConfig{opts: opts, noColor: true})
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+		got := next.(model)
+		if !slices.Equal(got.codes, codes) {
+			t.Errorf("%+v: x replaced a code: %v, want %v", opts, got.codes, codes)
+		}
+		if got.err != nil || got.status == "" {
+			t.Errorf("%+v: x gave error %v and status %q, want a status saying why", opts, got.err, got.status)
+		}
+	}
+}